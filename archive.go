@@ -0,0 +1,102 @@
+package sitetools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"path"
+	"time"
+)
+
+// archiveTime is the modification time of every file in an archive, so
+// archives of the same assets are identical. It is the earliest time a zip
+// file can hold.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteZip writes the assets to w as a zip archive, laid out as Write would lay
+// them out in a directory. Files are in order of their path and all have the
+// same timestamp, so the same assets always give the same archive.
+func (assets Assets) WriteZip(w io.Writer) error {
+	names, byName, err := assets.outputFiles()
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		header := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: archiveTime,
+		}
+		header.SetMode(0644)
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(byName[name].Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteTar is like WriteZip, but writes a tar archive.
+func (assets Assets) WriteTar(w io.Writer) error {
+	names, byName, err := assets.outputFiles()
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		data := byName[name].Data
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     int64(len(data)),
+			Mode:     0644,
+			ModTime:  archiveTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// WriteTarGz is like WriteTar, but compresses the archive with gzip.
+func (assets Assets) WriteTarGz(w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	if err := assets.WriteTar(gw); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// FromZip loads every file under root in the zip archive in r, which is size
+// bytes long, like FromDir. Use "." to load the whole archive, e.g. one written
+// by WriteZip.
+func (build *Build) FromZip(r io.ReaderAt, size int64, root string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	if root == "" {
+		root = "."
+	}
+
+	assets, err := build.walkDir(zr, path.Clean(root))
+	if err != nil {
+		return err
+	}
+
+	build.Assets = append(build.Assets, assets...)
+	return nil
+}
@@ -0,0 +1,165 @@
+package sitetools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+)
+
+func newArchiveTestAssets() Assets {
+	return Assets{
+		newTestAsset("/index.html", "<p>home</p>", nil),
+		newTestAsset("/blog/first.html", "first", nil),
+		newTestAsset("css/../style.css", "body {}", nil),
+		newTestAsset("/../../escape.txt", "escape", nil),
+		newTestAsset("/index.html", "<p>home, replaced</p>", nil),
+	}
+}
+
+var archiveTestExpected = []struct{ name, data string }{
+	{"blog/first.html", "first"},
+	{"escape.txt", "escape"},
+	{"index.html", "<p>home, replaced</p>"},
+	{"style.css", "body {}"},
+}
+
+func TestAssets_WriteZip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newArchiveTestAssets().WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip() returned error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read zip: %v", err)
+	}
+	if len(zr.File) != len(archiveTestExpected) {
+		t.Fatalf("expected %d files, got %d", len(archiveTestExpected), len(zr.File))
+	}
+	for i, f := range zr.File {
+		want := archiveTestExpected[i]
+		if f.Name != want.name {
+			t.Errorf("file %d = %s, want %s", i, f.Name, want.name)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(data) != want.data {
+			t.Errorf("%s = %q, want %q", f.Name, data, want.data)
+		}
+		if !f.Modified.Equal(archiveTime) {
+			t.Errorf("%s modified %v, want %v", f.Name, f.Modified, archiveTime)
+		}
+	}
+
+	// the same assets in a different order give the same archive
+	assets := newArchiveTestAssets()
+	assets[0], assets[2] = assets[2], assets[0]
+	var again bytes.Buffer
+	if err := assets.WriteZip(&again); err != nil {
+		t.Fatalf("WriteZip() returned error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("WriteZip() is not reproducible")
+	}
+}
+
+func TestAssets_WriteTar(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		var buf bytes.Buffer
+		var err error
+		if gzipped {
+			err = newArchiveTestAssets().WriteTarGz(&buf)
+		} else {
+			err = newArchiveTestAssets().WriteTar(&buf)
+		}
+		if err != nil {
+			t.Fatalf("write (gzipped: %v) returned error: %v", gzipped, err)
+		}
+
+		var r io.Reader = &buf
+		if gzipped {
+			gr, err := gzip.NewReader(&buf)
+			if err != nil {
+				t.Fatalf("failed to read gzip: %v", err)
+			}
+			r = gr
+		}
+
+		tr := tar.NewReader(r)
+		for i, want := range archiveTestExpected {
+			header, err := tr.Next()
+			if err != nil {
+				t.Fatalf("entry %d (gzipped: %v): %v", i, gzipped, err)
+			}
+			data, _ := io.ReadAll(tr)
+			if header.Name != want.name || string(data) != want.data {
+				t.Errorf("entry %d = %s %q, want %s %q", i, header.Name, data, want.name, want.data)
+			}
+			if !header.ModTime.Equal(archiveTime) {
+				t.Errorf("%s modified %v, want %v", header.Name, header.ModTime, archiveTime)
+			}
+		}
+		if _, err := tr.Next(); err != io.EOF {
+			t.Errorf("expected end of archive, got %v", err)
+		}
+	}
+}
+
+func TestAssets_WriteArchive_InvalidPath(t *testing.T) {
+	assets := Assets{newTestAsset("/", "root", nil)}
+
+	if err := assets.WriteZip(io.Discard); err == nil {
+		t.Error("WriteZip() expected error for an asset without a file name, got nil")
+	}
+	if err := assets.WriteTar(io.Discard); err == nil {
+		t.Error("WriteTar() expected error for an asset without a file name, got nil")
+	}
+	if err := assets.Write(t.TempDir()); err == nil {
+		t.Error("Write() expected error for an asset without a file name, got nil")
+	}
+}
+
+func TestBuild_FromZip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newArchiveTestAssets().WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip() returned error: %v", err)
+	}
+
+	build := &Build{}
+	if err := build.FromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "."); err != nil {
+		t.Fatalf("FromZip() returned error: %v", err)
+	}
+	if len(build.Assets) != len(archiveTestExpected) {
+		t.Fatalf("expected %d assets, got %d", len(archiveTestExpected), len(build.Assets))
+	}
+	for i, want := range archiveTestExpected {
+		asset := build.Assets[i]
+		if asset.Path != "/"+want.name || string(asset.Data) != want.data {
+			t.Errorf("asset %d = %s %q, want /%s %q", i, asset.Path, asset.Data, want.name, want.data)
+		}
+		if modTime, ok := asset.Meta["ModTime"].(time.Time); !ok || !modTime.Equal(archiveTime) {
+			t.Errorf("asset %s ModTime = %v, want %v", asset.Path, asset.Meta["ModTime"], archiveTime)
+		}
+	}
+
+	sub := &Build{}
+	if err := sub.FromZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "blog"); err != nil {
+		t.Fatalf("FromZip() returned error: %v", err)
+	}
+	if len(sub.Assets) != 1 || sub.Assets[0].Path != "/first.html" {
+		t.Errorf("FromZip() of blog = %v", assetPaths(sub.Assets))
+	}
+
+	if err := (&Build{}).FromZip(bytes.NewReader([]byte("not a zip")), 9, "."); err == nil {
+		t.Error("expected error for invalid zip, got nil")
+	}
+}
@@ -1,11 +1,15 @@
 package sitetools
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 )
 
 type Asset struct {
@@ -18,20 +22,116 @@ type Assets []*Asset
 
 func (assets *Assets) Add(newAssets ...Asset) {
 	for i := range newAssets {
-		cleaned := path.Clean("/" + strings.TrimPrefix(newAssets[i].Path, "/"))
+		cleaned := cleanPath(newAssets[i].Path)
 		newAssets[i].Path = cleaned
 		*assets = append(*assets, &newAssets[i])
 	}
 }
 
+// Transform runs each transformer over every asset in turn, stopping at the
+// first error, which is returned as a *BuildError.
 func (assets Assets) Transform(transformers ...Transformer) error {
+	return assets.TransformContext(context.Background(), transformers...)
+}
+
+// TransformContext is like Transform, but stops with the context's error once
+// ctx is cancelled. The context is checked before each asset, and transformers
+// implementing ContextTransformer, such as ImageTranscoder and
+// EncryptionTransformer, can also stop partway through an asset.
+func (assets Assets) TransformContext(ctx context.Context, transformers ...Transformer) error {
+	errs, err := assets.transform(ctx, false, transformers)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return err
+}
+
+// TransformCollect is like Transform, but carries on past errors so a single
+// run reports every failure. An asset that fails is skipped by the remaining
+// transformers. The failures are returned as BuildErrors.
+func (assets Assets) TransformCollect(transformers ...Transformer) error {
+	if errs, _ := assets.transform(context.Background(), true, transformers); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// transform runs the transformers over the assets, returning the failures and,
+// if it stopped early because ctx was cancelled, the context's error.
+func (assets Assets) transform(ctx context.Context, continueOnError bool, transformers []Transformer) (BuildErrors, error) {
+	var errs BuildErrors
+	failed := make([]bool, len(assets))
+
 	for _, transformer := range transformers {
-		for _, asset := range assets {
-			if err := transformer.Transform(asset); err != nil {
-				return err
+		ct := AdaptContext(transformer)
+		for i, asset := range assets {
+			if err := ctx.Err(); err != nil {
+				return errs, err
+			}
+			if failed[i] {
+				continue
 			}
+
+			assetPath := asset.Path
+			if err := ct.TransformContext(ctx, asset); err != nil {
+				errs = append(errs, newBuildError(assetPath, transformer, err))
+				if !continueOnError {
+					return errs, nil
+				}
+				failed[i] = true
+			}
+		}
+	}
+	return errs, nil
+}
+
+// TransformParallel is like Transform, but processes up to workers assets at
+// once (GOMAXPROCS when workers < 1). Each asset still passes through the
+// transformers in order, but an asset may reach a later transformer before
+// another has finished an earlier one, so transformers must not depend on other
+// assets in the set being transformed, such as a TemplateTransformer whose
+// Components are in it too.
+//
+// An error stops the remaining transformers for that asset only. The failures
+// of all assets are returned as BuildErrors, in the order of the assets.
+func (assets Assets) TransformParallel(workers int, transformers ...Transformer) error {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	errs := make([]*BuildError, len(assets))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(assets)) {
+		wg.Go(func() {
+			for i := range jobs {
+				for _, transformer := range transformers {
+					assetPath := assets[i].Path
+					if err := transformer.Transform(assets[i]); err != nil {
+						errs[i] = newBuildError(assetPath, transformer, err)
+						break
+					}
+				}
+			}
+		})
+	}
+
+	for i := range assets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failures BuildErrors
+	for _, err := range errs {
+		if err != nil {
+			failures = append(failures, err)
 		}
 	}
+	if len(failures) > 0 {
+		return failures
+	}
 	return nil
 }
 
@@ -42,8 +142,10 @@ func (assets Assets) Write(outDir string) error {
 	}
 
 	for _, asset := range assets {
-		cleaned := path.Clean("/" + strings.TrimPrefix(asset.Path, "/"))
-		rel := strings.TrimPrefix(cleaned, "/")
+		rel, err := outputName(asset)
+		if err != nil {
+			return err
+		}
 
 		target := filepath.Join(baseDir, filepath.FromSlash(rel))
 		targetAbs, err := filepath.Abs(target)
@@ -66,6 +168,50 @@ func (assets Assets) Write(outDir string) error {
 	return nil
 }
 
+// cleanPath returns p in the form of an asset's Path: cleaned and starting
+// with a slash, so it can't refer outside of the site. Paths are compared and
+// indexed in this form throughout, whichever way they were written.
+func cleanPath(p string) string {
+	return path.Clean("/" + strings.TrimPrefix(p, "/"))
+}
+
+// assetName returns the name of an asset within Assets as an fs.FS, which is
+// its cleaned Path without the leading slash.
+func assetName(asset *Asset) string {
+	return strings.TrimPrefix(cleanPath(asset.Path), "/")
+}
+
+// outputName returns the path an asset is written to, relative to the output
+// directory or archive root. Like Path, it can't refer outside of it.
+func outputName(asset *Asset) (string, error) {
+	name := assetName(asset)
+	if name == "" {
+		return "", fmt.Errorf("asset path is not a file: %q", asset.Path)
+	}
+	return name, nil
+}
+
+// outputFiles returns the output names of the assets, sorted, and the asset
+// written to each. When several assets have the same Path, the last one wins,
+// as with Write.
+func (assets Assets) outputFiles() ([]string, map[string]*Asset, error) {
+	byName := make(map[string]*Asset, len(assets))
+	for _, asset := range assets {
+		name, err := outputName(asset)
+		if err != nil {
+			return nil, nil, err
+		}
+		byName[name] = asset
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, byName, nil
+}
+
 func (assets Assets) ToMap(keyFromMeta string) map[string]*Asset {
 	m := make(map[string]*Asset)
 	for _, asset := range assets {
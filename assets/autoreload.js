@@ -1,2 +1,77 @@
-const socket = new WebSocket("ws://" + location.host + "{{.WEBSOCKET_PATH}}")
-socket.onclose = () => setTimeout(() => location.reload(true),{{.TIMEOUT}})
\ No newline at end of file
+// wrapped so nothing leaks into the page's globals
+(() => {
+  const socketURL = {{.URL}} || (location.protocol === "https:" ? "wss://" : "ws://") + ({{.HOST}} || location.host) + {{.WEBSOCKET_PATH}}
+  let reconnectDelay = Math.max({{.TIMEOUT}}, 100)
+  let reconnecting = false
+
+  function connect() {
+    const socket = new WebSocket(socketURL)
+    socket.onopen = () => {
+      // the server restarted while we were away, so the page may be stale
+      if (reconnecting) {
+        location.reload(true)
+      }
+    }
+    socket.onmessage = (event) => {
+      const message = JSON.parse(event.data)
+      switch (message.type) {
+        case "css":
+          swapStylesheets(message.paths)
+          break
+        case "error":
+          showErrorOverlay(message)
+          break
+        default:
+          location.reload(true)
+      }
+    }
+    socket.onclose = () => {
+      reconnecting = true
+      setTimeout(connect, reconnectDelay)
+      reconnectDelay = Math.min(reconnectDelay * 2, {{.MAX_TIMEOUT}})
+    }
+  }
+  connect()
+
+  function swapStylesheets(paths) {
+    document.querySelectorAll("link[rel=stylesheet]").forEach((link) => {
+      const url = new URL(link.href, location.href)
+      if (url.origin !== location.origin || !paths.includes(url.pathname)) {
+        return
+      }
+      url.searchParams.set("reload", Date.now())
+      const replacement = link.cloneNode()
+      replacement.href = url.href
+      replacement.onload = () => link.remove()
+      link.after(replacement)
+    })
+  }
+
+  function showErrorOverlay(error) {
+    document.getElementById("site-tools-error")?.remove()
+
+    const overlay = document.createElement("div")
+    overlay.id = "site-tools-error"
+    overlay.style.cssText = "position:fixed;inset:0;z-index:2147483647;overflow:auto;padding:2em;background:rgba(0,0,0,.85);color:#fff;font:14px/1.5 monospace"
+
+    const close = document.createElement("button")
+    close.textContent = "×"
+    close.title = "Dismiss"
+    close.style.cssText = "float:right;font-size:2em;background:none;border:0;color:inherit;cursor:pointer"
+    close.onclick = () => overlay.remove()
+
+    const title = document.createElement("h2")
+    title.textContent = "Build failed"
+    title.style.color = "#ff6b6b"
+
+    const details = document.createElement("p")
+    details.textContent = [error.file, error.stage].filter(Boolean).join(" · ")
+
+    const message = document.createElement("pre")
+    message.textContent = error.message
+    message.style.whiteSpace = "pre-wrap"
+
+    overlay.append(close, title, details, message)
+    document.body.append(overlay)
+  }
+})()
@@ -0,0 +1,186 @@
+package sitetools
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Open implements fs.FS, so the assets can be used like any other file system,
+// e.g. with http.FileServerFS or fs.WalkDir. Each asset is a file at its Path,
+// without the leading slash, and directories are implied by the paths. Files
+// have the permissions in the asset's "Mode" meta (default: 0444) and the time
+// in its "ModTime" meta. When several assets have the same Path, the last one
+// wins, as it would when writing them.
+func (assets Assets) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	for _, asset := range slices.Backward(assets) {
+		if assetName(asset) == name {
+			return &assetFile{Reader: bytes.NewReader(asset.Data), info: newAssetInfo(asset)}, nil
+		}
+	}
+
+	// a directory exists if any asset is in it
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	for _, asset := range slices.Backward(assets) {
+		rest, ok := strings.CutPrefix(assetName(asset), prefix)
+		if !ok || rest == "" {
+			continue
+		}
+
+		child, _, isDir := strings.Cut(rest, "/")
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+
+		if isDir {
+			entries = append(entries, fs.FileInfoToDirEntry(assetInfo{name: child, dir: true}))
+		} else {
+			entries = append(entries, fs.FileInfoToDirEntry(newAssetInfo(asset)))
+		}
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return &assetDir{info: assetInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+// assetIndex is like Assets as an fs.FS, but indexed up front, so opening a file
+// or directory doesn't scan every asset. Walking a large tree of assets, as
+// FromGit does, would otherwise take time quadratic in the number of files.
+type assetIndex struct {
+	files map[string]*Asset
+	dirs  map[string][]fs.DirEntry
+}
+
+func newAssetIndex(assets Assets) assetIndex {
+	idx := assetIndex{files: map[string]*Asset{}, dirs: map[string][]fs.DirEntry{".": nil}}
+	for _, asset := range assets {
+		idx.files[assetName(asset)] = asset
+	}
+
+	for name, asset := range idx.files {
+		entry := fs.FileInfoToDirEntry(newAssetInfo(asset))
+		for {
+			dir := path.Dir(name)
+			_, seen := idx.dirs[dir]
+			idx.dirs[dir] = append(idx.dirs[dir], entry)
+			if seen || dir == "." {
+				break
+			}
+			name = dir
+			entry = fs.FileInfoToDirEntry(assetInfo{name: path.Base(dir), dir: true})
+		}
+	}
+	for _, entries := range idx.dirs {
+		slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+	}
+	return idx
+}
+
+func (idx assetIndex) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if asset, ok := idx.files[name]; ok {
+		return &assetFile{Reader: bytes.NewReader(asset.Data), info: newAssetInfo(asset)}, nil
+	}
+	if entries, ok := idx.dirs[name]; ok {
+		return &assetDir{info: assetInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// assetInfo describes an asset or implied directory as an fs.FileInfo.
+type assetInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	dir     bool
+}
+
+func newAssetInfo(asset *Asset) assetInfo {
+	mode, _ := asset.Meta["Mode"].(fs.FileMode)
+	modTime, _ := asset.Meta["ModTime"].(time.Time)
+	return assetInfo{
+		name:    path.Base(assetName(asset)),
+		size:    int64(len(asset.Data)),
+		mode:    mode.Perm(),
+		modTime: modTime,
+	}
+}
+
+func (i assetInfo) Name() string       { return i.name }
+func (i assetInfo) Size() int64        { return i.size }
+func (i assetInfo) ModTime() time.Time { return i.modTime }
+func (i assetInfo) IsDir() bool        { return i.dir }
+func (i assetInfo) Sys() any           { return nil }
+
+func (i assetInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	if i.mode != 0 {
+		return i.mode
+	}
+	return 0444
+}
+
+// assetFile is an open asset.
+type assetFile struct {
+	*bytes.Reader
+	info assetInfo
+}
+
+func (f *assetFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *assetFile) Close() error               { return nil }
+
+// assetDir is an open directory of assets.
+type assetDir struct {
+	info    assetInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *assetDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *assetDir) Close() error               { return nil }
+
+func (d *assetDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *assetDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(remaining), nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(remaining))
+	d.offset += n
+	return slices.Clone(remaining[:n]), nil
+}
@@ -0,0 +1,106 @@
+package sitetools
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAssets_FS(t *testing.T) {
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assets := Assets{
+		newTestAsset("/index.html", "home", map[string]any{"ModTime": modTime}),
+		newTestAsset("/blog/index.html", "blog", nil),
+		newTestAsset("/blog/posts/first.html", "first", nil),
+		newTestAsset("css/style.css", "body {}", nil),
+		newTestAsset("/blog/posts/first.html", "first, replaced", nil),
+	}
+
+	if err := fstest.TestFS(assets, "index.html", "blog/index.html", "blog/posts/first.html", "css/style.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(assets, "blog/posts/first.html")
+	if err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+	if string(data) != "first, replaced" {
+		t.Errorf("ReadFile() = %q, want the last asset with the path", data)
+	}
+
+	info, err := fs.Stat(assets, "index.html")
+	if err != nil {
+		t.Fatalf("Stat() returned error: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) || info.Size() != 4 || info.IsDir() {
+		t.Errorf("Stat() = %v, %d, %v", info.ModTime(), info.Size(), info.IsDir())
+	}
+
+	entries, err := fs.ReadDir(assets, ".")
+	if err != nil {
+		t.Fatalf("ReadDir() returned error: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 3 || names[0] != "blog" || names[1] != "css" || names[2] != "index.html" {
+		t.Errorf("ReadDir(\".\") = %v", names)
+	}
+
+	for _, name := range []string{"missing.html", "blog/missing", "/index.html", "../index.html"} {
+		if _, err := assets.Open(name); err == nil {
+			t.Errorf("Open(%q) returned nil error", name)
+		}
+	}
+	if _, err := assets.Open("missing.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open() of a missing asset = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if _, err := fs.ReadDir(Assets{}, "."); err != nil {
+		t.Errorf("ReadDir() of no assets returned error: %v", err)
+	}
+}
+
+func TestAssetIndex(t *testing.T) {
+	assets := Assets{
+		newTestAsset("/index.html", "home", nil),
+		newTestAsset("/blog/index.html", "blog", nil),
+		newTestAsset("/blog/posts/first.html", "first", nil),
+		newTestAsset("css/style.css", "body {}", nil),
+		newTestAsset("/blog/posts/first.html", "first, replaced", nil),
+	}
+	idx := newAssetIndex(assets)
+
+	if err := fstest.TestFS(idx, "index.html", "blog/index.html", "blog/posts/first.html", "css/style.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	// it lists the same as walking Assets itself
+	list := func(fsys fs.FS) []string {
+		t.Helper()
+		var names []string
+		err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			names = append(names, name)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("WalkDir() returned error: %v", err)
+		}
+		return names
+	}
+	if got, want := list(idx), list(assets); !slices.Equal(got, want) {
+		t.Errorf("walking the index = %v, want %v", got, want)
+	}
+
+	data, err := fs.ReadFile(idx, "blog/posts/first.html")
+	if err != nil || string(data) != "first, replaced" {
+		t.Errorf("ReadFile() = %q, %v, want the last asset with the path", data, err)
+	}
+	if _, err := idx.Open("missing.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open() of a missing file = %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -1,10 +1,17 @@
 package sitetools
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func newTestAsset(path string, data string, meta map[string]any) *Asset {
@@ -24,6 +31,13 @@ func (m *MockTransformer) Transform(asset *Asset) error {
 	return nil
 }
 
+// transformFunc is a stateless Transformer, safe to share between goroutines.
+type transformFunc func(*Asset) error
+
+func (f transformFunc) Transform(asset *Asset) error {
+	return f(asset)
+}
+
 func TestAssets_Add(t *testing.T) {
 	assets := Assets{}
 
@@ -111,9 +125,199 @@ func TestAssets_Transform(t *testing.T) {
 	err = assetsSingle.Transform(failingTransformer)
 	if err == nil {
 		t.Errorf("Transform() with failing transformer did not return an error")
-	} else if err != os.ErrPermission {
+	} else if !errors.Is(err, os.ErrPermission) {
 		t.Errorf("Transform() with failing transformer returned wrong error: got %v, want %v", err, os.ErrPermission)
 	}
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("Transform() error %T is not a *BuildError", err)
+	}
+	if buildErr.Path != "fail.txt" {
+		t.Errorf("BuildError.Path = %s, want fail.txt", buildErr.Path)
+	}
+	if buildErr.Transformer != "*sitetools.MockTransformer" {
+		t.Errorf("BuildError.Transformer = %s, want *sitetools.MockTransformer", buildErr.Transformer)
+	}
+}
+
+func TestAssets_TransformCollect(t *testing.T) {
+	assets := Assets{
+		newTestAsset("/1.html", "{{ .Broken ", nil),
+		newTestAsset("/2.html", "ok", nil),
+		newTestAsset("/3.html", "{{ template \"missing\" }}", nil),
+	}
+
+	after := &MockTransformer{}
+	err := assets.TransformCollect(TemplateTransformer{}, after)
+	if err == nil {
+		t.Fatal("TransformCollect() did not return an error")
+	}
+
+	var buildErrs BuildErrors
+	if !errors.As(err, &buildErrs) {
+		t.Fatalf("TransformCollect() error %T is not BuildErrors", err)
+	}
+	if len(buildErrs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(buildErrs), err)
+	}
+	for i, wantPath := range []string{"/1.html", "/3.html"} {
+		if buildErrs[i].Path != wantPath {
+			t.Errorf("error %d Path = %s, want %s", i, buildErrs[i].Path, wantPath)
+		}
+		if buildErrs[i].Transformer != "sitetools.TemplateTransformer" {
+			t.Errorf("error %d Transformer = %s, want sitetools.TemplateTransformer", i, buildErrs[i].Transformer)
+		}
+	}
+
+	// failed assets are skipped by later transformers
+	if after.CalledCount != 1 {
+		t.Errorf("later transformer was called %d times, want 1", after.CalledCount)
+	}
+
+	if err := (Assets{newTestAsset("/ok.html", "ok", nil)}).TransformCollect(TemplateTransformer{}); err != nil {
+		t.Errorf("TransformCollect() without failures returned error: %v", err)
+	}
+}
+
+func TestAssets_TransformContext(t *testing.T) {
+	assets := Assets{
+		newTestAsset("1.txt", "", nil),
+		newTestAsset("2.txt", "", nil),
+		newTestAsset("3.txt", "", nil),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancelling := &MockTransformer{
+		TransformFunc: func(a *Asset) error {
+			if a.Path == "2.txt" {
+				cancel()
+			}
+			return nil
+		},
+	}
+	native := &mockContextTransformer{}
+
+	err := assets.TransformContext(ctx, native, cancelling)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("TransformContext() returned %v, want %v", err, context.Canceled)
+	}
+	if native.ContextCalledCount != 3 {
+		t.Errorf("TransformContext was called %d times on ContextTransformer, want 3", native.ContextCalledCount)
+	}
+	if native.CalledCount != 3 {
+		t.Errorf("ContextTransformer was called %d times, want 3", native.CalledCount)
+	}
+	if cancelling.CalledCount != 2 {
+		t.Errorf("transformer was called %d times after cancellation, want 2", cancelling.CalledCount)
+	}
+}
+
+func TestAssets_TransformParallel(t *testing.T) {
+	var assets Assets
+	for i := range 50 {
+		assets = append(assets, newTestAsset(fmt.Sprintf("/%d.txt", i), "", map[string]any{}))
+	}
+
+	var inFlight, maxInFlight atomic.Int32
+	stage := func(name string) Transformer {
+		return transformFunc(func(a *Asset) error {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			a.Data = append(a.Data, name...)
+			return nil
+		})
+	}
+
+	err := assets.TransformParallel(4, stage("a"), stage("b"), stage("c"))
+	if err != nil {
+		t.Fatalf("TransformParallel() returned error: %v", err)
+	}
+
+	for _, asset := range assets {
+		if string(asset.Data) != "abc" {
+			t.Errorf("asset %s Data = %q, want %q", asset.Path, asset.Data, "abc")
+		}
+	}
+	if maxInFlight.Load() < 2 || maxInFlight.Load() > 4 {
+		t.Errorf("max concurrent transforms = %d, want between 2 and 4", maxInFlight.Load())
+	}
+}
+
+func TestAssets_TransformParallel_BuiltinTransformers(t *testing.T) {
+	var assets Assets
+	for i := range 20 {
+		assets = append(assets, newTestAsset(fmt.Sprintf("/%d.md", i), "---\nTitle: Post\n---\n# {{ .Title }}\n\n```go\nfunc main() {}\n```\n", nil))
+	}
+
+	err := assets.TransformParallel(4,
+		CollectFrontMatter{},
+		TemplateTransformer{Global: map[string]any{"SiteName": "Test"}},
+		MarkdownTransformer{},
+		MinifyTransformer{},
+	)
+	if err != nil {
+		t.Fatalf("TransformParallel() returned error: %v", err)
+	}
+
+	for _, asset := range assets {
+		if path.Ext(asset.Path) != ".html" {
+			t.Errorf("asset %s was not converted to HTML", asset.Path)
+		}
+		if !bytes.Contains(asset.Data, []byte("<h1>Post</h1><figure class=codeblock data-lang=go>")) {
+			t.Errorf("asset %s Data = %q, missing heading", asset.Path, asset.Data)
+		}
+	}
+}
+
+func TestAssets_TransformParallel_Errors(t *testing.T) {
+	var assets Assets
+	for i := range 10 {
+		assets = append(assets, newTestAsset(fmt.Sprintf("/%d.txt", i), "", nil))
+	}
+
+	failing := transformFunc(func(a *Asset) error {
+		if a.Path == "/3.txt" || a.Path == "/7.txt" {
+			return fmt.Errorf("failed %s", a.Path)
+		}
+		return nil
+	})
+	var after atomic.Int32
+	counting := transformFunc(func(a *Asset) error {
+		after.Add(1)
+		return nil
+	})
+
+	for range 5 {
+		after.Store(0)
+		err := assets.TransformParallel(0, failing, counting)
+		if err == nil {
+			t.Fatal("TransformParallel() did not return an error")
+		}
+		var buildErrs BuildErrors
+		if !errors.As(err, &buildErrs) || len(buildErrs) != 2 {
+			t.Fatalf("TransformParallel() error = %v, want 2 BuildErrors", err)
+		}
+		if buildErrs[0].Path != "/3.txt" || buildErrs[1].Path != "/7.txt" {
+			t.Errorf("TransformParallel() error = %q, want errors in asset order", err)
+		}
+		if after.Load() != 8 {
+			t.Errorf("later transformer ran %d times, want 8", after.Load())
+		}
+	}
+
+	if err := (Assets{}).TransformParallel(2, failing); err != nil {
+		t.Errorf("TransformParallel() on empty assets returned error: %v", err)
+	}
 }
 
 func TestAssets_Write(t *testing.T) {
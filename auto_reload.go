@@ -3,6 +3,7 @@ package sitetools
 import (
 	"bytes"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"path"
 )
@@ -14,9 +15,25 @@ var autoreloadTemplate []byte
 // auto-reloading when a WebSocket connection is closed.
 // This is useful for development environments where you want the page to automatically
 // refresh when the server restarts or files change. Not really intended for production use.
+//
+// The script also acts on messages from a DevServer: it swaps changed stylesheets
+// in place and shows an overlay with the error when a build fails.
+//
+// It connects with wss:// on pages served over HTTPS and ws:// otherwise. When the
+// connection is lost, it keeps trying to reconnect, waiting Timeout milliseconds
+// before the first attempt and twice as long before each one after, up to
+// MaxTimeout, and reloads the page once it is back.
 type AddAutoReload struct {
 	WebSocketPath string
 	Timeout       int
+	// MaxTimeout caps the wait between reconnection attempts in milliseconds (default: 10000)
+	MaxTimeout int
+	// Host is the host and port to connect to (default: the page's), e.g. when the
+	// pages are served through a reverse proxy on another port
+	Host string
+	// URL is the full WebSocket URL to connect to, e.g. "wss://dev.example.com/ws",
+	// overriding WebSocketPath, Host and the scheme
+	URL string
 }
 
 func (auto AddAutoReload) Transform(asset *Asset) error {
@@ -34,11 +51,20 @@ func (auto AddAutoReload) Transform(asset *Asset) error {
 		Data: autoreloadTemplate,
 	}
 
-	// Use ReplacerTransformer to replace placeholders
+	maxTimeout := auto.MaxTimeout
+	if maxTimeout <= 0 {
+		maxTimeout = 10000
+	}
+
+	// Use ReplacerTransformer to replace placeholders, with strings as JSON so
+	// they are valid JavaScript literals
 	err := ReplacerTransformer{
 		Replacements: map[string]string{
-			"{{.WEBSOCKET_PATH}}": auto.WebSocketPath,
+			"{{.WEBSOCKET_PATH}}": jsString(auto.WebSocketPath),
+			"{{.HOST}}":           jsString(auto.Host),
+			"{{.URL}}":            jsString(auto.URL),
 			"{{.TIMEOUT}}":        fmt.Sprintf("%d", auto.Timeout),
+			"{{.MAX_TIMEOUT}}":    fmt.Sprintf("%d", maxTimeout),
 		},
 	}.Transform(scriptAsset)
 	if err != nil {
@@ -50,3 +76,11 @@ func (auto AddAutoReload) Transform(asset *Asset) error {
 
 	return nil
 }
+
+// jsString returns s as a JavaScript string literal that is safe to use in a
+// <script> element.
+func jsString(s string) string {
+	// json.Marshal escapes <, > and & so "</script>" can't end the element
+	data, _ := json.Marshal(s)
+	return string(data)
+}
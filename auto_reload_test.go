@@ -2,6 +2,7 @@ package sitetools
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -23,8 +24,14 @@ func TestAddAutoReload_Transform(t *testing.T) {
 				WebSocketPath: "/ws",
 				Timeout:       1000,
 			},
-			expectedData: []byte("<html><body><script>const socket = new WebSocket(\"ws://\" + location.host + \"/ws\")\nsocket.onclose = () => setTimeout(() => location.reload(true),1000)</script></body></html>"),
-			expectError:  false,
+			expectedData: []byte("<html><body><script>" + strings.NewReplacer(
+				"{{.WEBSOCKET_PATH}}", `"/ws"`,
+				"{{.HOST}}", `""`,
+				"{{.URL}}", `""`,
+				"{{.TIMEOUT}}", "1000",
+				"{{.MAX_TIMEOUT}}", "10000",
+			).Replace(string(autoreloadTemplate)) + "</script></body></html>"),
+			expectError: false,
 		},
 		{
 			name: "Non-HTML file",
@@ -73,3 +80,62 @@ func TestAddAutoReload_Transform(t *testing.T) {
 		})
 	}
 }
+
+func TestAddAutoReload_Script(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoReload AddAutoReload
+		contains   []string
+	}{
+		{
+			name:       "defaults",
+			autoReload: AddAutoReload{WebSocketPath: "/ws", Timeout: 1000},
+			contains: []string{
+				`const socketURL = "" || (location.protocol === "https:" ? "wss://" : "ws://") + ("" || location.host) + "/ws"`,
+				`Math.max(1000, 100)`,
+				`Math.min(reconnectDelay * 2, 10000)`,
+				`case "css":`,
+				`case "error":`,
+			},
+		},
+		{
+			name:       "host",
+			autoReload: AddAutoReload{WebSocketPath: "/ws", Host: "localhost:8080", MaxTimeout: 5000},
+			contains: []string{
+				`("localhost:8080" || location.host) + "/ws"`,
+				`Math.min(reconnectDelay * 2, 5000)`,
+			},
+		},
+		{
+			name:       "url",
+			autoReload: AddAutoReload{URL: "wss://dev.example.com/ws"},
+			contains:   []string{`const socketURL = "wss://dev.example.com/ws" ||`},
+		},
+		{
+			name:       "escaping",
+			autoReload: AddAutoReload{WebSocketPath: `/ws"</script>`},
+			contains:   []string{`"/ws\"\u003c/script\u003e"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset := &Asset{Path: "index.html", Data: []byte("<body></body>")}
+			if err := tt.autoReload.Transform(asset); err != nil {
+				t.Fatalf("Transform() returned error: %v", err)
+			}
+
+			for _, want := range tt.contains {
+				if !bytes.Contains(asset.Data, []byte(want)) {
+					t.Errorf("script does not contain %q", want)
+				}
+			}
+			if bytes.Contains(asset.Data, []byte("{{.")) {
+				t.Errorf("script has unreplaced placeholders: %s", asset.Data)
+			}
+			if bytes.Count(asset.Data, []byte("</script>")) != 1 {
+				t.Errorf("script element is not closed exactly once: %s", asset.Data)
+			}
+		})
+	}
+}
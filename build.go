@@ -3,44 +3,194 @@ package sitetools
 import (
 	"bytes"
 	"io/fs"
+	"mime"
+	"net/http"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 type Build struct {
 	Assets
+	// TrimSpace opts text assets into having leading and trailing whitespace
+	// trimmed when they are loaded. A text asset is trimmed only if it matches
+	// every filter, and nothing is trimmed when no filters are set. Binary
+	// assets are always loaded byte for byte.
+	TrimSpace []Filter
+	// Ignore lists gitignore-style patterns, relative to the loaded root, for
+	// files and directories to skip when loading. Patterns from an IgnoreFile in
+	// the root are applied after these. See DefaultIgnore for common patterns.
+	Ignore []string
 }
 
+// FromDir loads every file under root in fsys as an asset. Besides its Path
+// and Data, each asset records where it came from in its meta: "SourcePath"
+// (the path within fsys), "Size" (int64), "Mode" (fs.FileMode) and "ModTime"
+// (time.Time).
 func (build *Build) FromDir(fsys fs.FS, root string) error {
-	return build.walkDir(fsys, root)
+	assets, err := build.walkDir(fsys, root)
+	if err != nil {
+		return err
+	}
+
+	build.Assets = append(build.Assets, assets...)
+	return nil
+}
+
+// Layer is a single source root loaded by FromDirs.
+type Layer struct {
+	// Name is recorded in the "Layer" meta of each asset loaded from this layer
+	// (default: its index among the layers, e.g. "0", as layers often share a
+	// Root)
+	Name string
+	FS   fs.FS
+	// Root is the directory within FS to load (default: ".")
+	Root string
 }
 
-func (build *Build) walkDir(fsys fs.FS, root string) error {
-	return fs.WalkDir(fsys, root,
+// FromDirs loads each layer like FromDir and merges them in order, so an asset
+// from a later layer replaces one at the same Path from an earlier layer
+// instead of being added alongside it. A replacement takes the position of the
+// asset it overrides. Assets already in the build are not affected.
+func (build *Build) FromDirs(layers ...Layer) error {
+	var merged Assets
+	index := map[string]int{}
+
+	for i, layer := range layers {
+		root := layer.Root
+		if root == "" {
+			root = "."
+		}
+		name := layer.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+
+		assets, err := build.walkDir(layer.FS, root)
+		if err != nil {
+			return err
+		}
+
+		for _, asset := range assets {
+			asset.Meta["Layer"] = name
+
+			if i, ok := index[asset.Path]; ok {
+				merged[i] = asset
+				continue
+			}
+			index[asset.Path] = len(merged)
+			merged = append(merged, asset)
+		}
+	}
+
+	build.Assets = append(build.Assets, merged...)
+	return nil
+}
+
+func (build *Build) walkDir(fsys fs.FS, root string) (Assets, error) {
+	ignoreLines, err := readIgnoreFile(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := parseIgnore(append(slices.Clone(build.Ignore), ignoreLines...))
+	if err != nil {
+		return nil, err
+	}
+
+	var assets Assets
+	err = fs.WalkDir(fsys, root,
 		func(filepath string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
 
+			rel := relPath(root, filepath)
+			if rel != "" && (rel == IgnoreFile || rules.ignored(rel, d.IsDir())) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+
 			if d.IsDir() {
 				return nil
 			}
 
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
 			data, err := fs.ReadFile(fsys, filepath)
 			if err != nil {
 				return err
 			}
 
-			assetPath := strings.TrimPrefix(filepath, root)
-			assetPath = path.Clean("/" + strings.TrimPrefix(assetPath, "/"))
+			asset := &Asset{
+				Path: path.Clean("/" + rel),
+				Meta: map[string]any{
+					"SourcePath": filepath,
+					"Size":       info.Size(),
+					"Mode":       info.Mode(),
+					"ModTime":    info.ModTime(),
+				},
+				Data: data,
+			}
+			if build.shouldTrim(*asset) {
+				asset.Data = bytes.TrimSpace(asset.Data)
+			}
 
-			build.Assets = append(build.Assets, &Asset{
-				Path: assetPath,
-				Meta: map[string]any{},
-				Data: bytes.TrimSpace(data),
-			})
+			assets = append(assets, asset)
 
 			return nil
 		},
 	)
+	return assets, err
+}
+
+// relPath returns name relative to root, both being paths in the same fs.FS.
+func relPath(root, name string) string {
+	if root == "." {
+		return name
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(name, root), "/")
+}
+
+func (build *Build) shouldTrim(asset Asset) bool {
+	if len(build.TrimSpace) == 0 || !isText(asset) {
+		return false
+	}
+	for _, filter := range build.TrimSpace {
+		if !filter(asset) {
+			return false
+		}
+	}
+	return true
+}
+
+// isText reports whether an asset holds text, going by the MIME type of its
+// extension when that is known and by sniffing its contents otherwise. Like
+// git, anything with a NUL byte near the start is treated as binary.
+func isText(asset Asset) bool {
+	if bytes.IndexByte(asset.Data[:min(512, len(asset.Data))], 0) != -1 {
+		return false
+	}
+
+	textTypes := []string{
+		"text/*",
+		"application/javascript",
+		"application/json",
+		"application/xml",
+		"application/yaml",
+		"image/svg+xml",
+	}
+	if WithMimeType(textTypes...)(asset) {
+		return true
+	}
+	if mime.TypeByExtension(path.Ext(asset.Path)) != "" {
+		return false
+	}
+
+	return strings.HasPrefix(http.DetectContentType(asset.Data), "text/")
 }
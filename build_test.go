@@ -1,9 +1,14 @@
 package sitetools
 
 import (
+	"bytes"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestFromDir(t *testing.T) {
@@ -38,6 +43,32 @@ func TestFromDir(t *testing.T) {
 	}
 }
 
+func TestFromDir_Metadata(t *testing.T) {
+	modTime := time.Date(2025, 8, 2, 10, 30, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"content/posts/post.md": {Data: []byte("hello"), Mode: 0640, ModTime: modTime},
+	}
+
+	build := &Build{}
+	if err := build.FromDir(fsys, "content"); err != nil {
+		t.Fatalf("Failed: %v", err)
+	}
+
+	if len(build.Assets) != 1 {
+		t.Fatalf("expected 1 asset, got %d", len(build.Assets))
+	}
+
+	expectedMeta := map[string]any{
+		"SourcePath": "content/posts/post.md",
+		"Size":       int64(5),
+		"Mode":       fs.FileMode(0640),
+		"ModTime":    modTime,
+	}
+	if !reflect.DeepEqual(build.Assets[0].Meta, expectedMeta) {
+		t.Errorf("Meta = %v, want %v", build.Assets[0].Meta, expectedMeta)
+	}
+}
+
 func TestFromDir_ReadFileError(t *testing.T) {
 	dir := t.TempDir()
 
@@ -54,3 +85,175 @@ func TestFromDir_ReadFileError(t *testing.T) {
 		t.Fatal("expected error due to file read permissions, got nil")
 	}
 }
+
+func TestFromDir_PreservesBinaryFiles(t *testing.T) {
+	build := &Build{TrimSpace: []Filter{WithExtensions(".png", ".jpg", ".gif", ".webp", ".avif")}}
+
+	err := build.FromDir(os.DirFS("test_assets"), "images")
+	if err != nil {
+		t.Fatalf("Failed: %v", err)
+	}
+
+	images := build.Filter(WithMimeType("image/*"))
+	if len(images) == 0 {
+		t.Fatal("expected image assets, got none")
+	}
+
+	for _, asset := range images {
+		expected, err := os.ReadFile(filepath.Join("test_assets", "images", filepath.FromSlash(asset.Path)))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", asset.Path, err)
+		}
+		if !bytes.Equal(asset.Data, expected) {
+			t.Errorf("asset %s was modified on load: got %d bytes, want %d", asset.Path, len(asset.Data), len(expected))
+		}
+	}
+}
+
+func TestFromDir_TrimSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	binary := []byte("\n\x00\x01\x02binary\x00\x01 \n")
+	testFiles := map[string][]byte{
+		"page.md":    []byte("\n# Title\n\n"),
+		"notes.txt":  []byte("  notes\n"),
+		"data.bin":   binary,
+		"unknown":    []byte("\nplain text\n"),
+		"binary.md":  binary,
+		"script.js":  []byte("\nconsole.log(1)\n"),
+		"styles.css": []byte("\nbody {}\n"),
+	}
+	for name, data := range testFiles {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		trim     []Filter
+		expected map[string][]byte
+	}{
+		{
+			name:     "no trimming by default",
+			expected: testFiles,
+		},
+		{
+			name: "trim matching text files",
+			trim: []Filter{WithExtensions(".md", ".txt", ".bin", "")},
+			expected: map[string][]byte{
+				"page.md":    []byte("# Title"),
+				"notes.txt":  []byte("notes"),
+				"data.bin":   binary,
+				"unknown":    []byte("plain text"),
+				"binary.md":  binary,
+				"script.js":  []byte("\nconsole.log(1)\n"),
+				"styles.css": []byte("\nbody {}\n"),
+			},
+		},
+		{
+			name: "trim all text files",
+			trim: []Filter{func(Asset) bool { return true }},
+			expected: map[string][]byte{
+				"page.md":    []byte("# Title"),
+				"notes.txt":  []byte("notes"),
+				"data.bin":   binary,
+				"unknown":    []byte("plain text"),
+				"binary.md":  binary,
+				"script.js":  []byte("console.log(1)"),
+				"styles.css": []byte("body {}"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := &Build{TrimSpace: tt.trim}
+			if err := build.FromDir(os.DirFS(dir), "."); err != nil {
+				t.Fatalf("Failed: %v", err)
+			}
+
+			if len(build.Assets) != len(tt.expected) {
+				t.Fatalf("expected %d assets, got %d", len(tt.expected), len(build.Assets))
+			}
+			for _, asset := range build.Assets {
+				want := tt.expected[asset.Path[1:]]
+				if !bytes.Equal(asset.Data, want) {
+					t.Errorf("asset %s data = %q, want %q", asset.Path, asset.Data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFromDirs(t *testing.T) {
+	theme := fstest.MapFS{
+		"theme/templates/base.html":  {Data: []byte("theme base")},
+		"theme/templates/post.html":  {Data: []byte("theme post")},
+		"theme/static/styles.css":    {Data: []byte("theme css")},
+		"theme/components/nav.html":  {Data: []byte("theme nav")},
+		"theme/components/foot.html": {Data: []byte("theme foot")},
+	}
+	site := fstest.MapFS{
+		"templates/post.html": {Data: []byte("site post")},
+		"static/styles.css":   {Data: []byte("site css")},
+		"index.md":            {Data: []byte("site index")},
+	}
+	local := fstest.MapFS{
+		"static/styles.css": {Data: []byte("local css")},
+	}
+
+	build := &Build{Assets: Assets{newTestAsset("/static/styles.css", "existing", map[string]any{})}}
+	err := build.FromDirs(
+		Layer{Name: "theme", FS: theme, Root: "theme"},
+		Layer{Name: "site", FS: site},
+		Layer{FS: local, Root: "."},
+	)
+	if err != nil {
+		t.Fatalf("FromDirs() returned error: %v", err)
+	}
+
+	expected := []struct {
+		path  string
+		data  string
+		layer any
+	}{
+		{"/static/styles.css", "existing", nil},
+		{"/components/foot.html", "theme foot", "theme"},
+		{"/components/nav.html", "theme nav", "theme"},
+		{"/static/styles.css", "local css", "2"},
+		{"/templates/base.html", "theme base", "theme"},
+		{"/templates/post.html", "site post", "site"},
+		{"/index.md", "site index", "site"},
+	}
+
+	if len(build.Assets) != len(expected) {
+		t.Fatalf("expected %d assets, got %d", len(expected), len(build.Assets))
+	}
+	for i, want := range expected {
+		asset := build.Assets[i]
+		if asset.Path != want.path {
+			t.Errorf("asset %d Path = %s, want %s", i, asset.Path, want.path)
+		}
+		if string(asset.Data) != want.data {
+			t.Errorf("asset %s Data = %q, want %q", asset.Path, asset.Data, want.data)
+		}
+		if asset.Meta["Layer"] != want.layer {
+			t.Errorf("asset %s Meta[\"Layer\"] = %v, want %v", asset.Path, asset.Meta["Layer"], want.layer)
+		}
+	}
+}
+
+func TestFromDirs_Error(t *testing.T) {
+	build := &Build{}
+	err := build.FromDirs(
+		Layer{FS: fstest.MapFS{"index.html": {Data: []byte("index")}}},
+		Layer{FS: fstest.MapFS{}, Root: "missing"},
+	)
+	if err == nil {
+		t.Fatal("expected error for missing layer root, got nil")
+	}
+	if len(build.Assets) != 0 {
+		t.Errorf("expected no assets after error, got %d", len(build.Assets))
+	}
+}
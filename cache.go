@@ -0,0 +1,221 @@
+package sitetools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CachedTransformer wraps a Transformer and stores its output in Dir, so when
+// the same asset reaches the same transformer again, on this run or a later
+// one, the stored output is used instead of transforming it again. This is
+// most useful for slow transformers such as ImageTranscoder and
+// EncryptionTransformer.
+//
+// Entries are keyed by the asset's Path, Data and Meta, the transformer's type
+// and its configuration as JSON, and Version. As the meta includes where the
+// asset was loaded from and when it was modified, touching a file or a fresh
+// checkout misses the cache, unless IgnoreSourceMeta is set. As the
+// configuration includes the contents of assets the transformer holds,
+// changing the Template of a WrapperTemplateTransformer or one of the
+// Components or Global data of a TemplateTransformer invalidates every entry
+// that used it. Change Version when anything else the output depends on
+// changes, e.g. after upgrading.
+//
+// Transformers whose configuration cannot be encoded as JSON, such as ones
+// holding funcs, are run without caching, as are assets whose meta cannot be.
+type CachedTransformer struct {
+	Transformer
+	// Dir is the directory entries are stored in. Required.
+	Dir string
+	// Version is part of every key, so changing it invalidates all entries.
+	Version string
+	// IgnoreSourceMeta leaves the meta describing the file an asset was loaded
+	// from ("SourcePath", "Size", "Mode" and "ModTime") out of the key, both
+	// the asset's and that of the assets the configuration holds. The current
+	// values are kept when an entry is used. Only set it for transformers whose
+	// output doesn't depend on them, such as ImageTranscoder, and not for
+	// templates, which can show e.g. {{ .ModTime }}.
+	IgnoreSourceMeta bool
+}
+
+// cacheEntry is the output of a transformer as stored on disk.
+type cacheEntry struct {
+	Path string
+	Data []byte
+	Meta map[string]any
+	// HasMeta distinguishes empty from nil Meta, which gob does not.
+	HasMeta bool
+}
+
+var registerCacheTypes sync.Once
+
+func (c CachedTransformer) Transform(asset *Asset) error {
+	return c.TransformContext(context.Background(), asset)
+}
+
+func (c CachedTransformer) TransformContext(ctx context.Context, asset *Asset) error {
+	if c.Dir == "" {
+		return fmt.Errorf("cache dir is required")
+	}
+	registerCacheTypes.Do(func() {
+		gob.Register(map[string]any{})
+		gob.Register(map[any]any{})
+		gob.Register([]any{})
+		gob.Register([]string{})
+		gob.Register([]map[string]any{})
+		gob.Register(time.Time{})
+		gob.Register(fs.FileMode(0))
+	})
+
+	transformer := AdaptContext(c.Transformer)
+
+	key, ok := c.key(asset)
+	if !ok {
+		return transformer.TransformContext(ctx, asset)
+	}
+	entryPath := filepath.Join(c.Dir, key[:2], key)
+
+	if data, err := os.ReadFile(entryPath); err == nil {
+		source := asset.Meta
+		var entry cacheEntry
+		if gob.NewDecoder(bytes.NewReader(data)).Decode(&entry) == nil {
+			asset.Path = entry.Path
+			asset.Data = entry.Data
+			asset.Meta = entry.Meta
+			if entry.HasMeta && asset.Meta == nil {
+				asset.Meta = map[string]any{}
+			}
+			if c.IgnoreSourceMeta && asset.Meta != nil {
+				for _, k := range sourceMetaKeys {
+					if v, ok := source[k]; ok {
+						asset.Meta[k] = v
+					} else {
+						delete(asset.Meta, k)
+					}
+				}
+			}
+			return nil
+		}
+	}
+
+	if err := transformer.TransformContext(ctx, asset); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cacheEntry{
+		Path:    asset.Path,
+		Data:    asset.Data,
+		Meta:    asset.Meta,
+		HasMeta: asset.Meta != nil,
+	}); err != nil {
+		return nil
+	}
+	return writeFileAtomic(entryPath, buf.Bytes())
+}
+
+// key returns the cache key for asset, or false if the asset or transformer
+// can't be serialised.
+func (c CachedTransformer) key(asset *Asset) (string, bool) {
+	config, err := json.Marshal(c.Transformer)
+	if err != nil {
+		return "", false
+	}
+	if c.IgnoreSourceMeta {
+		// assets the transformer holds, such as templates, carry source meta too
+		var decoded any
+		if err := json.Unmarshal(config, &decoded); err != nil {
+			return "", false
+		}
+		if config, err = json.Marshal(withoutSourceMeta(decoded)); err != nil {
+			return "", false
+		}
+	}
+	// normalizeYAML would turn nil meta into an empty map, so keep the two apart
+	meta := []byte("null")
+	if asset.Meta != nil {
+		hashed := make(map[string]any, len(asset.Meta))
+		for k, v := range asset.Meta {
+			if !c.IgnoreSourceMeta || !slices.Contains(sourceMetaKeys, k) {
+				hashed[k] = v
+			}
+		}
+		if meta, err = json.Marshal(normalizeYAML(hashed)); err != nil {
+			return "", false
+		}
+	}
+
+	h := sha256.New()
+	for _, part := range [][]byte{
+		[]byte(fmt.Sprintf("%T", c.Transformer)),
+		config,
+		[]byte(c.Version),
+		[]byte(asset.Path),
+		asset.Data,
+		meta,
+	} {
+		// length prefix each part so they can't run into one another
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// withoutSourceMeta removes sourceMetaKeys from the "Meta" of every asset in a
+// transformer's configuration decoded from JSON.
+func withoutSourceMeta(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if meta, ok := child.(map[string]any); ok && k == "Meta" {
+				for _, key := range sourceMetaKeys {
+					delete(meta, key)
+				}
+			}
+			withoutSourceMeta(child)
+		}
+	case []any:
+		for _, child := range v {
+			withoutSourceMeta(child)
+		}
+	}
+	return v
+}
+
+// writeFileAtomic writes data to a temporary file next to name and renames it
+// into place, so readers never see a partially written file.
+func writeFileAtomic(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
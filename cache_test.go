@@ -0,0 +1,310 @@
+package sitetools
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// countingTransformer appends Suffix to each asset and counts its calls. The
+// counter is unexported so it is not part of the cache key.
+type countingTransformer struct {
+	Suffix string
+	calls  *int
+	err    error
+}
+
+func (c countingTransformer) Transform(asset *Asset) error {
+	*c.calls++
+	if c.err != nil {
+		return c.err
+	}
+	asset.Data = append(asset.Data, c.Suffix...)
+	asset.Path += ".out"
+	if asset.Meta != nil {
+		asset.Meta["Transformed"] = 1
+	}
+	return nil
+}
+
+func countCacheEntries(t *testing.T, dir string) int {
+	t.Helper()
+
+	count := 0
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if d != nil && !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk cache dir: %v", err)
+	}
+	return count
+}
+
+func TestCachedTransformer(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+
+	newAsset := func() *Asset {
+		return newTestAsset("/page.html", "data", map[string]any{
+			"Title":   "Page",
+			"ModTime": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			"Nested":  map[any]any{"key": []any{"a", 1}},
+		})
+	}
+
+	cached := CachedTransformer{Transformer: countingTransformer{Suffix: "-x", calls: &calls}, Dir: dir}
+
+	first := newAsset()
+	if err := cached.Transform(first); err != nil {
+		t.Fatalf("Transform() returned error: %v", err)
+	}
+
+	second := newAsset()
+	if err := cached.Transform(second); err != nil {
+		t.Fatalf("Transform() returned error: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("transformer was called %d times, want 1", calls)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("cached asset = %+v, want %+v", second, first)
+	}
+	if second.Path != "/page.html.out" || string(second.Data) != "data-x" || second.Meta["Transformed"] != 1 {
+		t.Errorf("cached asset has unexpected contents: %+v", second)
+	}
+
+	// a new CachedTransformer, as on the next run, reuses the same entries
+	reused := CachedTransformer{Transformer: countingTransformer{Suffix: "-x", calls: &calls}, Dir: dir}
+	if err := reused.Transform(newAsset()); err != nil {
+		t.Fatalf("Transform() returned error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("transformer was called %d times across runs, want 1", calls)
+	}
+
+	// empty and nil meta survive the round trip
+	for _, meta := range []map[string]any{nil, {}} {
+		for range 2 {
+			asset := &Asset{Path: "/meta.txt"}
+			if meta != nil {
+				asset.Meta = map[string]any{}
+			}
+			if err := cached.Transform(asset); err != nil {
+				t.Fatalf("Transform() returned error: %v", err)
+			}
+			if (asset.Meta == nil) != (meta == nil) {
+				t.Errorf("Meta = %#v, want nil: %v", asset.Meta, meta == nil)
+			}
+		}
+	}
+}
+
+func TestCachedTransformer_Invalidation(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+
+	transform := func(c CachedTransformer, asset *Asset) {
+		t.Helper()
+		c.Dir = dir
+		if err := c.Transform(asset); err != nil {
+			t.Fatalf("Transform() returned error: %v", err)
+		}
+	}
+
+	base := CachedTransformer{Transformer: countingTransformer{Suffix: "-x", calls: &calls}}
+	transform(base, newTestAsset("/a.html", "data", nil))
+
+	tests := []struct {
+		name        string
+		transformer CachedTransformer
+		asset       *Asset
+	}{
+		{"data", base, newTestAsset("/a.html", "other", nil)},
+		{"path", base, newTestAsset("/b.html", "data", nil)},
+		{"meta", base, newTestAsset("/a.html", "data", map[string]any{"Title": "A"})},
+		{"configuration", CachedTransformer{Transformer: countingTransformer{Suffix: "-y", calls: &calls}}, newTestAsset("/a.html", "data", nil)},
+		{"version", CachedTransformer{Transformer: countingTransformer{Suffix: "-x", calls: &calls}, Version: "2"}, newTestAsset("/a.html", "data", nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := calls
+			transform(tt.transformer, tt.asset)
+			if calls != before+1 {
+				t.Errorf("changing the %s did not invalidate the cache", tt.name)
+			}
+		})
+	}
+}
+
+func TestCachedTransformer_TemplateInvalidation(t *testing.T) {
+	dir := t.TempDir()
+
+	render := func(wrapper string) string {
+		t.Helper()
+		asset := newTestAsset("/page.html", "content", map[string]any{})
+		cached := CachedTransformer{
+			Transformer: WrapperTemplateTransformer{
+				WrapperTemplate: WrapperTemplate{
+					Template:       newTestAsset("/wrapper.html", wrapper, nil),
+					ChildBlockName: "child",
+				},
+			},
+			Dir: dir,
+		}
+		if err := cached.Transform(asset); err != nil {
+			t.Fatalf("Transform() returned error: %v", err)
+		}
+		return string(asset.Data)
+	}
+
+	if got := render(`<main>{{ template "child" . }}</main>`); got != "<main>content</main>" {
+		t.Errorf("first render = %q", got)
+	}
+	if got := render(`<body>{{ template "child" . }}</body>`); got != "<body>content</body>" {
+		t.Errorf("render after template change = %q, want the new template", got)
+	}
+	if n := countCacheEntries(t, dir); n != 2 {
+		t.Errorf("expected 2 cache entries, got %d", n)
+	}
+}
+
+// heldAssetTransformer is a countingTransformer configured with an asset, as a
+// template transformer is.
+type heldAssetTransformer struct {
+	countingTransformer
+	Held *Asset
+}
+
+func TestCachedTransformer_SourceMeta(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, src, "page.html", "content")
+	writeTestFile(t, src, "held.txt", "held")
+
+	load := func() (*Asset, *Asset) {
+		t.Helper()
+		build := &Build{}
+		if err := build.FromDir(os.DirFS(src), "."); err != nil {
+			t.Fatalf("FromDir() returned error: %v", err)
+		}
+		return build.Filter(WithPath("/page.html"))[0], build.Filter(WithPath("/held.txt"))[0]
+	}
+	touch := func(modTime time.Time) {
+		t.Helper()
+		for _, name := range []string{"page.html", "held.txt"} {
+			if err := os.Chtimes(filepath.Join(src, name), modTime, modTime); err != nil {
+				t.Fatalf("failed to touch %s: %v", name, err)
+			}
+		}
+	}
+
+	t.Run("ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		calls := 0
+		transform := func() *Asset {
+			t.Helper()
+			page, held := load()
+			cached := CachedTransformer{
+				Transformer:      heldAssetTransformer{countingTransformer{Suffix: "-x", calls: &calls}, held},
+				Dir:              dir,
+				IgnoreSourceMeta: true,
+			}
+			if err := cached.Transform(page); err != nil {
+				t.Fatalf("Transform() returned error: %v", err)
+			}
+			return page
+		}
+
+		transform()
+		// touching the files, as a fresh checkout would, keeps the entry
+		modTime := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		touch(modTime)
+		page := transform()
+
+		if calls != 1 {
+			t.Errorf("transformer was called %d times after touching the files, want 1", calls)
+		}
+		if n := countCacheEntries(t, dir); n != 1 {
+			t.Errorf("cache has %d entries after touching the files, want 1", n)
+		}
+		if string(page.Data) != "content-x" {
+			t.Errorf("cached output = %q, want %q", page.Data, "content-x")
+		}
+		if got, _ := page.Meta["ModTime"].(time.Time); !got.Equal(modTime) {
+			t.Errorf("ModTime = %v, want the reloaded %v", got, modTime)
+		}
+	})
+
+	t.Run("kept", func(t *testing.T) {
+		dir := t.TempDir()
+		render := func() string {
+			t.Helper()
+			page, _ := load()
+			cached := CachedTransformer{Transformer: TemplateTransformer{}, Dir: dir}
+			if err := cached.Transform(page); err != nil {
+				t.Fatalf("Transform() returned error: %v", err)
+			}
+			return string(page.Data)
+		}
+
+		writeTestFile(t, src, "page.html", `{{ .ModTime.Year }}`)
+		touch(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+		if got := render(); got != "2030" {
+			t.Fatalf("first render = %q, want 2030", got)
+		}
+		// by default a template showing the time is rendered again
+		touch(time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC))
+		if got := render(); got != "2031" {
+			t.Errorf("render after touching the page = %q, want 2031", got)
+		}
+	})
+}
+
+func TestCachedTransformer_Uncacheable(t *testing.T) {
+	dir := t.TempDir()
+
+	mock := &MockTransformer{TransformFunc: func(a *Asset) error { return nil }}
+	cached := CachedTransformer{Transformer: mock, Dir: dir}
+	for range 2 {
+		if err := cached.Transform(newTestAsset("/a.html", "data", nil)); err != nil {
+			t.Fatalf("Transform() returned error: %v", err)
+		}
+	}
+	if mock.CalledCount != 2 {
+		t.Errorf("transformer with funcs was called %d times, want 2", mock.CalledCount)
+	}
+	if n := countCacheEntries(t, dir); n != 0 {
+		t.Errorf("expected no cache entries, got %d", n)
+	}
+}
+
+func TestCachedTransformer_Errors(t *testing.T) {
+	calls := 0
+	failing := CachedTransformer{
+		Transformer: countingTransformer{calls: &calls, err: os.ErrPermission},
+		Dir:         t.TempDir(),
+	}
+	for range 2 {
+		if err := failing.Transform(newTestAsset("/a.html", "data", nil)); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("Transform() = %v, want %v", err, os.ErrPermission)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("failing transformer was called %d times, want 2 (errors are not cached)", calls)
+	}
+
+	if err := (CachedTransformer{Transformer: countingTransformer{calls: &calls}}).Transform(newTestAsset("/a.html", "", nil)); err == nil {
+		t.Error("expected error without Dir, got nil")
+	}
+}
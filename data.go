@@ -0,0 +1,149 @@
+package sitetools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+var dataExtensions = []string{".yaml", ".yml", ".json", ".toml", ".csv"}
+
+// PopData removes the data files (YAML, JSON, TOML and CSV) under dir from the
+// build and returns their parsed contents, keyed by their path relative to dir
+// without the extension. Files in subdirectories are nested, so with dir
+// "/data", "/data/authors.yaml" is returned as data["authors"] and
+// "/data/nav/main.json" as data["nav"]["main"]. With dir "/", every data file
+// in the build is popped.
+//
+// The result is meant for TemplateTransformer.Global, for example
+// Global: map[string]any{"Data": data} makes the authors available to
+// templates as {{ .Global.Data.authors }}. The data files are returned too, for
+// TemplateTransformer.Data, so the pages record them as dependencies.
+func (build *Build) PopData(dir string) (map[string]any, Assets, error) {
+	dir = cleanPath(dir)
+
+	// WithParentDir matches nothing for the root, where every asset is under dir
+	inDir := WithParentDir(dir)
+	if dir == "/" {
+		inDir = func(Asset) bool { return true }
+	}
+
+	data := map[string]any{}
+	dirs := map[string]map[string]any{}
+	files := build.Pop(inDir, WithExtensions(dataExtensions...))
+	for _, asset := range files {
+		value, err := parseData(*asset)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rel := strings.TrimPrefix(asset.Path, strings.TrimSuffix(dir, "/")+"/")
+		keys := strings.Split(strings.TrimSuffix(rel, path.Ext(rel)), "/")
+
+		parent := data
+		for i, key := range keys[:len(keys)-1] {
+			subdir := strings.Join(keys[:i+1], "/")
+			child, ok := dirs[subdir]
+			if !ok {
+				if _, exists := parent[key]; exists {
+					return nil, nil, fmt.Errorf("issue in asset %s: data key %q is already defined", asset.Path, key)
+				}
+				child = map[string]any{}
+				dirs[subdir] = child
+				parent[key] = child
+			}
+			parent = child
+		}
+
+		key := keys[len(keys)-1]
+		if _, exists := parent[key]; exists {
+			return nil, nil, fmt.Errorf("issue in asset %s: data key %q is already defined", asset.Path, key)
+		}
+		parent[key] = value
+	}
+
+	return data, files, nil
+}
+
+// parseData decodes a data asset based on its file extension. CSV files are
+// returned as a list of records keyed by the header row.
+func parseData(asset Asset) (any, error) {
+	var value any
+	var err error
+
+	switch path.Ext(asset.Path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(asset.Data, &value)
+		value = normalizeYAML(value)
+	case ".json":
+		err = json.Unmarshal(asset.Data, &value)
+	case ".toml":
+		table := map[string]any{}
+		err = toml.Unmarshal(asset.Data, &table)
+		value = table
+	case ".csv":
+		value, err = parseCSV(asset.Data)
+	default:
+		err = fmt.Errorf("unsupported data file type")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("issue in asset %s: %w", asset.Path, err)
+	}
+	return value, nil
+}
+
+func parseCSV(data []byte) ([]map[string]any, error) {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	records := []map[string]any{}
+	if len(rows) == 0 {
+		return records, nil
+	}
+
+	header := rows[0]
+	for _, row := range rows[1:] {
+		record := make(map[string]any, len(header))
+		for i, column := range header {
+			record[column] = row[i]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// normalizeYAML converts the map[any]any values produced by yaml.v2 into
+// map[string]any, so YAML data behaves the same as JSON and TOML data.
+func normalizeYAML(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, val := range v {
+			m[key] = normalizeYAML(val)
+		}
+		return m
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(val)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i := range v {
+			s[i] = normalizeYAML(v[i])
+		}
+		return s
+	default:
+		return v
+	}
+}
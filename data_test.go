@@ -0,0 +1,153 @@
+package sitetools
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPopData(t *testing.T) {
+	build := &Build{
+		Assets: Assets{
+			newTestAsset("/index.html", `{{ range .Global.Data.authors }}{{ .name }};{{ end }}{{ .Global.Data.nav.main.home }}`, map[string]any{}),
+			newTestAsset("/data/authors.yaml", "- name: Ada\n  tags: [math]\n- name: Grace\n  meta: {navy: true}\n", map[string]any{}),
+			newTestAsset("/data/nav/main.json", `{"home": "/", "items": [1, 2]}`, map[string]any{}),
+			newTestAsset("/data/site.toml", "title = \"My Site\"\n[social]\nmastodon = \"@me\"\n", map[string]any{}),
+			newTestAsset("/data/products.csv", "slug,price\nwidget,10\ngadget,20\n", map[string]any{}),
+			newTestAsset("/data/readme.txt", "not data", map[string]any{}),
+			newTestAsset("/other/ignored.json", `{}`, map[string]any{}),
+		},
+	}
+
+	data, files, err := build.PopData("/data")
+	if err != nil {
+		t.Fatalf("PopData() returned error: %v", err)
+	}
+	expectedFiles := []string{"/data/authors.yaml", "/data/nav/main.json", "/data/site.toml", "/data/products.csv"}
+	if paths := assetPaths(files); !reflect.DeepEqual(paths, expectedFiles) {
+		t.Errorf("PopData() files = %v, want %v", paths, expectedFiles)
+	}
+
+	expected := map[string]any{
+		"authors": []any{
+			map[string]any{"name": "Ada", "tags": []any{"math"}},
+			map[string]any{"name": "Grace", "meta": map[string]any{"navy": true}},
+		},
+		"nav": map[string]any{
+			"main": map[string]any{"home": "/", "items": []any{1.0, 2.0}},
+		},
+		"site": map[string]any{
+			"title":  "My Site",
+			"social": map[string]any{"mastodon": "@me"},
+		},
+		"products": []map[string]any{
+			{"slug": "widget", "price": "10"},
+			{"slug": "gadget", "price": "20"},
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("PopData() = %#v, want %#v", data, expected)
+	}
+
+	var remaining []string
+	for _, asset := range build.Assets {
+		remaining = append(remaining, asset.Path)
+	}
+	expectedRemaining := []string{"/index.html", "/data/readme.txt", "/other/ignored.json"}
+	if !reflect.DeepEqual(remaining, expectedRemaining) {
+		t.Errorf("remaining assets = %v, want %v", remaining, expectedRemaining)
+	}
+
+	err = build.Filter(WithPath("/index.html")).Transform(TemplateTransformer{
+		Global: map[string]any{"Data": data},
+		Data:   files,
+	})
+	if err != nil {
+		t.Fatalf("Transform() returned error: %v", err)
+	}
+	if got := string(build.Assets[0].Data); got != "Ada;Grace;/" {
+		t.Errorf("template output = %q, want %q", got, "Ada;Grace;/")
+	}
+	expectedDeps := []string{"/data/authors.yaml", "/data/nav/main.json", "/data/products.csv", "/data/site.toml"}
+	if deps := dependencies(build.Assets[0]); !reflect.DeepEqual(deps, expectedDeps) {
+		t.Errorf("Dependencies = %v, want %v", deps, expectedDeps)
+	}
+}
+
+func TestPopData_Root(t *testing.T) {
+	build := &Build{
+		Assets: Assets{
+			newTestAsset("/index.html", "<p>home</p>", map[string]any{}),
+			newTestAsset("/site.yaml", "title: My Site\n", map[string]any{}),
+			newTestAsset("/nav/main.json", `{"home": "/"}`, map[string]any{}),
+		},
+	}
+
+	for _, dir := range []string{"/", "", "."} {
+		t.Run(dir, func(t *testing.T) {
+			build := &Build{Assets: slices.Clone(build.Assets)}
+			data, _, err := build.PopData(dir)
+			if err != nil {
+				t.Fatalf("PopData() returned error: %v", err)
+			}
+
+			expected := map[string]any{
+				"site": map[string]any{"title": "My Site"},
+				"nav":  map[string]any{"main": map[string]any{"home": "/"}},
+			}
+			if !reflect.DeepEqual(data, expected) {
+				t.Errorf("PopData() = %#v, want %#v", data, expected)
+			}
+			if paths := assetPaths(build.Assets); !reflect.DeepEqual(paths, []string{"/index.html"}) {
+				t.Errorf("remaining assets = %v, want [/index.html]", paths)
+			}
+		})
+	}
+}
+
+func TestPopData_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets Assets
+	}{
+		{
+			name:   "invalid yaml",
+			assets: Assets{newTestAsset("/data/a.yaml", "key: [unclosed", nil)},
+		},
+		{
+			name:   "invalid json",
+			assets: Assets{newTestAsset("/data/a.json", "{", nil)},
+		},
+		{
+			name:   "invalid toml",
+			assets: Assets{newTestAsset("/data/a.toml", "key = ", nil)},
+		},
+		{
+			name:   "invalid csv",
+			assets: Assets{newTestAsset("/data/a.csv", "a,b\n1\n", nil)},
+		},
+		{
+			name: "duplicate key",
+			assets: Assets{
+				newTestAsset("/data/a.yaml", "a: 1", nil),
+				newTestAsset("/data/a.json", `{"a": 1}`, nil),
+			},
+		},
+		{
+			name: "file and directory with the same key",
+			assets: Assets{
+				newTestAsset("/data/nav.yaml", "a: 1", nil),
+				newTestAsset("/data/nav/main.yaml", "a: 1", nil),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := &Build{Assets: tt.assets}
+			if _, _, err := build.PopData("data"); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package sitetools
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+)
+
+// sourceMetaKeys are the meta keys set when an asset is loaded that describe
+// the file it came from rather than its contents.
+var sourceMetaKeys = []string{"SourcePath", "Size", "Mode", "ModTime"}
+
+// sourceID identifies the source an asset was loaded from: its "SourcePath"
+// meta when it has one, and its Path otherwise.
+func sourceID(asset *Asset) string {
+	if sourcePath, ok := asset.Meta["SourcePath"].(string); ok && sourcePath != "" {
+		return sourcePath
+	}
+	return asset.Path
+}
+
+// dependencies returns the "Dependencies" meta of an asset.
+func dependencies(asset *Asset) []string {
+	deps, _ := asset.Meta["Dependencies"].([]string)
+	return deps
+}
+
+// addDependencies records in the "Dependencies" meta of asset that it was built
+// from deps, along with everything they were built from in turn. The list is
+// kept sorted and free of duplicates, and never includes the asset itself.
+func addDependencies(asset *Asset, deps ...*Asset) {
+	if len(deps) == 0 {
+		return
+	}
+	if asset.Meta == nil {
+		asset.Meta = map[string]any{}
+	}
+
+	self := sourceID(asset)
+	ids := slices.Clone(dependencies(asset))
+	for _, dep := range deps {
+		if dep == nil {
+			continue
+		}
+		ids = append(ids, sourceID(dep))
+		ids = append(ids, dependencies(dep)...)
+	}
+
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	ids = slices.DeleteFunc(ids, func(id string) bool { return id == self })
+	asset.Meta["Dependencies"] = ids
+}
+
+// Affected returns the assets of the build that need to be rebuilt when the
+// sources in changedPaths do, going by the "SourcePath" of the assets (or their
+// Path if they have none). An asset is affected when:
+//
+//   - it was loaded from one of the changed sources;
+//   - it is a text asset whose contents refer to the Path of a changed asset
+//     that is not a page, such as a page showing an image or a page loading a
+//     stylesheet; or
+//   - its "Dependencies" meta, as recorded by TemplateTransformer,
+//     WrapperTemplateTransformer and GeneratePages, includes an affected
+//     source.
+//
+// Pages linking to one another don't affect each other, so a nav linking to
+// every page doesn't make every page affected. Call it on a transformed build,
+// so the dependencies have been recorded.
+func (build *Build) Affected(changedPaths ...string) Assets {
+	affected := map[string]bool{}
+	for _, id := range changedPaths {
+		affected[id] = true
+	}
+
+	// only the assets that changed themselves are looked for in the contents of
+	// others, and only if they are not pages, which are linked rather than
+	// built into what refers to them
+	var refs [][]byte
+	for _, asset := range build.Assets {
+		if affected[sourceID(asset)] && !isPage(asset) {
+			refs = append(refs, []byte(asset.Path))
+		}
+	}
+
+	result := make([]bool, len(build.Assets))
+	for i, asset := range build.Assets {
+		if affected[sourceID(asset)] {
+			result[i] = true
+			continue
+		}
+		if isText(*asset) {
+			for _, ref := range refs {
+				if bytes.Contains(asset.Data, ref) {
+					result[i] = true
+					break
+				}
+			}
+		}
+	}
+	for i, asset := range build.Assets {
+		if result[i] {
+			affected[sourceID(asset)] = true
+		}
+	}
+
+	// dependencies are recorded with everything they were built from in turn,
+	// but an affected asset may itself be a dependency of others
+	for {
+		grew := false
+		for i, asset := range build.Assets {
+			if result[i] {
+				continue
+			}
+			for _, dep := range dependencies(asset) {
+				if affected[dep] {
+					result[i] = true
+					affected[sourceID(asset)] = true
+					grew = true
+					break
+				}
+			}
+		}
+		if !grew {
+			break
+		}
+	}
+
+	var assets Assets
+	for i, asset := range build.Assets {
+		if result[i] {
+			assets = append(assets, asset)
+		}
+	}
+	return assets
+}
+
+// isPage reports whether an asset is an HTML page.
+func isPage(asset *Asset) bool {
+	contentType, _, _ := strings.Cut(assetContentType(asset), ";")
+	return strings.TrimSpace(contentType) == "text/html"
+}
@@ -0,0 +1,232 @@
+package sitetools
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func assetPaths(assets Assets) []string {
+	var paths []string
+	for _, asset := range assets {
+		paths = append(paths, asset.Path)
+	}
+	return paths
+}
+
+func TestTransformers_RecordDependencies(t *testing.T) {
+	header := newTestAsset("/components/header.html", "<header>{{ .Title }}</header>", map[string]any{"SourcePath": "components/header.html"})
+	styles := newTestAsset("/components/styles.css", "body {}", map[string]any{"SourcePath": "components/styles.css"})
+	wrapper := newTestAsset("/layouts/base.html", `<main>{{ template "child" . }}</main>`, map[string]any{"SourcePath": "layouts/base.html"})
+
+	page := newTestAsset("/index.html", `{{ template "header" . }}`, map[string]any{"SourcePath": "index.html", "Title": "Home"})
+	components := map[string]*Asset{"header": header, "styles": styles}
+
+	if err := (TemplateTransformer{Components: components}).Transform(page); err != nil {
+		t.Fatalf("TemplateTransformer.Transform() returned error: %v", err)
+	}
+	expected := []string{"components/header.html"}
+	if deps := page.Meta["Dependencies"]; !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Dependencies after TemplateTransformer = %v, want %v", deps, expected)
+	}
+
+	wrapperTransformer := WrapperTemplateTransformer{
+		TemplateTransformer: TemplateTransformer{Components: components},
+		WrapperTemplate:     WrapperTemplate{Template: wrapper, ChildBlockName: "child"},
+	}
+	if err := wrapperTransformer.Transform(page); err != nil {
+		t.Fatalf("WrapperTemplateTransformer.Transform() returned error: %v", err)
+	}
+	expected = []string{"components/header.html", "layouts/base.html"}
+	if deps := page.Meta["Dependencies"]; !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Dependencies after WrapperTemplateTransformer = %v, want %v", deps, expected)
+	}
+	if page.Meta["SourcePath"] != "index.html" {
+		t.Errorf("SourcePath = %v, want index.html", page.Meta["SourcePath"])
+	}
+}
+
+func TestTransformers_RecordOnlyUsedComponents(t *testing.T) {
+	component := func(name, data string) *Asset {
+		return newTestAsset("/components/"+name+".html", data, map[string]any{"SourcePath": "components/" + name + ".html"})
+	}
+	components := map[string]*Asset{
+		"header": component("header", `<header>{{ template "nav" . }}</header>`),
+		"nav":    component("nav", "<nav></nav>"),
+		"icons":  component("icons", `{{ define "icon" }}<svg></svg>{{ end }}`),
+		"footer": component("footer", "<footer></footer>"),
+		"unused": component("unused", "<aside></aside>"),
+	}
+
+	page := newTestAsset("/index.html", `{{ template "header" . }}{{ range .Items }}{{ if . }}{{ template "icon" }}{{ end }}{{ end }}`, map[string]any{
+		"SourcePath": "index.html",
+		"Items":      []int{1},
+	})
+	if err := (TemplateTransformer{Components: components}).Transform(page); err != nil {
+		t.Fatalf("TemplateTransformer.Transform() returned error: %v", err)
+	}
+	expected := []string{"components/header.html", "components/icons.html", "components/nav.html"}
+	if deps := dependencies(page); !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Dependencies after TemplateTransformer = %v, want %v", deps, expected)
+	}
+
+	wrapper := newTestAsset("/layouts/base.html", `<main>{{ block "child" . }}{{ end }}</main>{{ template "footer" }}`, map[string]any{"SourcePath": "layouts/base.html"})
+	child := newTestAsset("/about.html", `{{ template "nav" }}`, map[string]any{"SourcePath": "about.html"})
+	wrapperTransformer := WrapperTemplateTransformer{
+		TemplateTransformer: TemplateTransformer{Components: components},
+		WrapperTemplate:     WrapperTemplate{Template: wrapper, ChildBlockName: "child"},
+	}
+	if err := wrapperTransformer.Transform(child); err != nil {
+		t.Fatalf("WrapperTemplateTransformer.Transform() returned error: %v", err)
+	}
+	expected = []string{"components/footer.html", "components/nav.html", "layouts/base.html"}
+	if deps := dependencies(child); !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Dependencies after WrapperTemplateTransformer = %v, want %v", deps, expected)
+	}
+}
+
+func TestGeneratePages_RecordsDependencies(t *testing.T) {
+	data := newTestAsset("/data/products.json", `[{"slug": "a"}]`, map[string]any{"SourcePath": "data/products.json"})
+	tmpl := newTestAsset("/templates/product.html", "{{ .slug }}", map[string]any{"SourcePath": "templates/product.html"})
+
+	build := &Build{}
+	if err := build.GeneratePages(data, tmpl, "/products/{{ .slug }}.html"); err != nil {
+		t.Fatalf("GeneratePages() returned error: %v", err)
+	}
+
+	page := build.Assets[0]
+	expected := []string{"data/products.json", "templates/product.html"}
+	if deps := page.Meta["Dependencies"]; !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Dependencies = %v, want %v", deps, expected)
+	}
+	if _, ok := page.Meta["SourcePath"]; ok {
+		t.Errorf("generated page inherited SourcePath %v", page.Meta["SourcePath"])
+	}
+}
+
+func TestWrapperTemplateTransformer_GeneratedPages(t *testing.T) {
+	data := newTestAsset("/data/products.json", `[{"slug": "a"}, {"slug": "b"}]`, map[string]any{"SourcePath": "data/products.json"})
+	tmpl := newTestAsset("/templates/product.html", "{{ .slug }}", map[string]any{"SourcePath": "templates/product.html"})
+	layout := newTestAsset("/layout.html", `<main>{{ template "child" . }}</main>`, map[string]any{
+		"SourcePath": "layout.html",
+		"ModTime":    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+
+	build := &Build{Assets: Assets{layout}}
+	if err := build.GeneratePages(data, tmpl, "/p/{{ .slug }}.html"); err != nil {
+		t.Fatalf("GeneratePages() returned error: %v", err)
+	}
+	err := build.Filter(WithParentDir("/p")).Transform(WrapperTemplateTransformer{
+		WrapperTemplate: WrapperTemplate{Template: layout, ChildBlockName: "child"},
+	})
+	if err != nil {
+		t.Fatalf("Transform() returned error: %v", err)
+	}
+
+	page := build.Assets[1]
+	if string(page.Data) != "<main>a</main>" {
+		t.Errorf("Data = %q, want %q", page.Data, "<main>a</main>")
+	}
+	for _, key := range []string{"SourcePath", "ModTime"} {
+		if v, ok := page.Meta[key]; ok {
+			t.Errorf("wrapped page inherited %s %v from the layout", key, v)
+		}
+	}
+	expected := []string{"data/products.json", "layout.html", "templates/product.html"}
+	if deps := dependencies(page); !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Dependencies = %v, want %v", deps, expected)
+	}
+
+	if got := assetPaths(build.Affected("templates/product.html")); !slices.Equal(got, []string{"/p/a.html", "/p/b.html"}) {
+		t.Errorf("Affected() = %v, want the generated pages only", got)
+	}
+}
+
+func TestBuild_Affected(t *testing.T) {
+	build := &Build{Assets: Assets{
+		newTestAsset("/index.html", `<img src="/img/logo.png">`, map[string]any{
+			"SourcePath":   "index.md",
+			"Dependencies": []string{"layouts/base.html"},
+		}),
+		newTestAsset("/about.html", "about", map[string]any{
+			"SourcePath":   "about.md",
+			"Dependencies": []string{"components/footer.html", "layouts/base.html"},
+		}),
+		newTestAsset("/blog.html", "blog", map[string]any{
+			"SourcePath":   "blog.md",
+			"Dependencies": []string{"components/nav.html"},
+		}),
+		newTestAsset("/img/logo.png", "\x89PNG\x00", map[string]any{"SourcePath": "img/logo.png"}),
+		// a page generated from data links to a page, which doesn't affect it
+		newTestAsset("/gallery.html", `<a href="/index.html">home</a>`, map[string]any{
+			"Dependencies": []string{"data/gallery.json"},
+		}),
+		newTestAsset("/robots.txt", "User-agent: *", nil),
+	}}
+
+	tests := []struct {
+		name     string
+		changed  []string
+		expected []string
+	}{
+		{"nothing", nil, nil},
+		{"unknown", []string{"missing.md"}, nil},
+		{"page", []string{"blog.md"}, []string{"/blog.html"}},
+		{"component", []string{"components/footer.html"}, []string{"/about.html"}},
+		{"layout", []string{"layouts/base.html"}, []string{"/index.html", "/about.html"}},
+		{"referenced image", []string{"img/logo.png"}, []string{"/index.html", "/img/logo.png"}},
+		{"linked page", []string{"index.md"}, []string{"/index.html"}},
+		{"data", []string{"data/gallery.json"}, []string{"/gallery.html"}},
+		{"asset without source", []string{"/robots.txt"}, []string{"/robots.txt"}},
+		{"several", []string{"blog.md", "components/footer.html"}, []string{"/about.html", "/blog.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := assetPaths(build.Affected(tt.changed...))
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Affected(%v) = %v, want %v", tt.changed, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuild_Affected_LinkedPages(t *testing.T) {
+	nav := `<nav><a href="/a.html">a</a><a href="/b.html">b</a><a href="/c.html">c</a></nav>`
+	page := func(name, body string) *Asset {
+		return newTestAsset("/"+name+".html", nav+body, map[string]any{
+			"SourcePath":   name + ".md",
+			"Dependencies": []string{"components/nav.html"},
+		})
+	}
+	build := &Build{Assets: Assets{
+		page("a", `<link rel="stylesheet" href="/style.css">`),
+		page("b", `<a href="/c.html">c</a>`),
+		page("c", `<a href="/b.html">b</a><script src="/app.js"></script>`),
+		page("d", `<a href="/c.html">c</a>`),
+		newTestAsset("/style.css", "body {}", map[string]any{"SourcePath": "style.css"}),
+		newTestAsset("/app.js", "run()", map[string]any{"SourcePath": "app.js"}),
+	}}
+
+	tests := []struct {
+		name     string
+		changed  []string
+		expected []string
+	}{
+		{"page", []string{"a.md"}, []string{"/a.html"}},
+		{"linked page", []string{"c.md"}, []string{"/c.html"}},
+		{"stylesheet", []string{"style.css"}, []string{"/a.html", "/style.css"}},
+		{"script", []string{"app.js"}, []string{"/c.html", "/app.js"}},
+		{"shared nav", []string{"components/nav.html"}, []string{"/a.html", "/b.html", "/c.html", "/d.html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := assetPaths(build.Affected(tt.changed...))
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Affected(%v) = %v, want %v", tt.changed, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package sitetools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DevServer serves the assets of a build from memory over HTTP for local
+// development, using an AssetHandler. While Run is going, it polls Source for
+// changes and calls Build again whenever something changes, then updates the
+// pages open in a browser through their WebSocket connections at
+// WebSocketPath, as expected by AddAutoReload. Build should add AddAutoReload
+// to its transformers with the same WebSocketPath.
+//
+// When only stylesheets changed, pages swap them in place rather than
+// reloading. When a build fails, pages show an overlay with the error, and the
+// last good build is served until the next one succeeds.
+//
+// A DevServer is an http.Handler, so it can be tested with httptest, or
+// started along with its watcher by ListenAndServe.
+type DevServer struct {
+	// Source is polled for changes to files, e.g. os.DirFS("."). Required.
+	Source fs.FS
+	// Build builds the site, initially and after each change. Its context is
+	// cancelled when a newer change makes the build obsolete. Required.
+	Build func(ctx context.Context) (*Build, error)
+	// WebSocketPath is where pages connect to be told to reload (default: "/ws")
+	WebSocketPath string
+	// Interval is how often Source is polled (default: 500ms)
+	Interval time.Duration
+	// Ignore lists gitignore-style patterns for files and directories in Source
+	// whose changes don't call for a rebuild (default: DefaultIgnore)
+	Ignore []string
+	// NotFound is the Path of the asset to serve for paths with no asset, see
+	// AssetHandler
+	NotFound string
+	// ErrorLog is where build errors are logged (default: the log package's
+	// standard logger)
+	ErrorLog *log.Logger
+
+	mu      sync.Mutex
+	assets  map[string]*Asset
+	handler *AssetHandler
+	err     error
+	clients map[net.Conn]bool
+}
+
+// ListenAndServe serves the build on addr, rebuilding it as Source changes,
+// until ctx is cancelled.
+func (s *DevServer) ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{Addr: addr, Handler: s}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- s.Run(ctx)
+		server.Close()
+	}()
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		// stop watching too, e.g. when addr is already in use
+		cancel()
+		<-runErr
+		return err
+	}
+	return <-runErr
+}
+
+// Run builds the site and then polls Source, rebuilding on every change, until
+// ctx is cancelled. Build errors are logged rather than returned, and the last
+// successful build is served until the next one succeeds.
+func (s *DevServer) Run(ctx context.Context) error {
+	if s.Source == nil {
+		return fmt.Errorf("dev server source is required")
+	}
+	if s.Build == nil {
+		return fmt.Errorf("dev server build func is required")
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	ignore := s.Ignore
+	if ignore == nil {
+		ignore = DefaultIgnore
+	}
+	rules, err := parseIgnore(ignore)
+	if err != nil {
+		return err
+	}
+
+	signature, err := sourceSignature(s.Source, rules)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// start a build, cancelling the previous one if it is still going
+	cancelBuild := func() {}
+	defer func() { cancelBuild() }()
+	rebuild := func() {
+		cancelBuild()
+		buildCtx, cancel := context.WithCancel(ctx)
+		cancelBuild = cancel
+		wg.Go(func() { s.rebuild(buildCtx) })
+	}
+	rebuild()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next, err := sourceSignature(s.Source, rules)
+		if err != nil {
+			s.logf("watching source: %v", err)
+			continue
+		}
+		if !bytes.Equal(next, signature) {
+			signature = next
+			rebuild()
+		}
+	}
+}
+
+// rebuild runs Build and, unless ctx was cancelled in the meantime, serves the
+// result and updates the connected pages.
+func (s *DevServer) rebuild(ctx context.Context) {
+	build, err := s.Build(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// checked under the lock, so a build that was superseded while waiting for
+	// it can't replace the newer one
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		s.err = err
+		s.logf("build failed: %v", err)
+		s.broadcast(newDevErrorMessage(err))
+		return
+	}
+
+	assets := map[string]*Asset{}
+	for _, asset := range build.Assets {
+		assets[cleanPath(asset.Path)] = asset
+	}
+
+	message := devMessage{Type: "reload"}
+	var changed []string
+	if s.err == nil && s.assets != nil {
+		var onlyCSS bool
+		changed, onlyCSS = changedAssets(s.assets, assets)
+		if onlyCSS {
+			message = devMessage{Type: "css", Paths: changed}
+		}
+	}
+	notify := s.err != nil || s.assets == nil || len(changed) > 0
+
+	s.err = nil
+	s.assets = assets
+	s.handler = &AssetHandler{Assets: build.Assets, NotFound: s.NotFound}
+	if notify {
+		s.broadcast(message)
+	}
+}
+
+// changedAssets returns the paths of the assets that were added, removed or
+// changed between two builds, and whether they are all stylesheets that were
+// changed in place.
+func changedAssets(previous, next map[string]*Asset) ([]string, bool) {
+	var changed []string
+	onlyCSS := true
+	for name, asset := range next {
+		old, ok := previous[name]
+		if ok && bytes.Equal(old.Data, asset.Data) {
+			continue
+		}
+		changed = append(changed, name)
+		onlyCSS = onlyCSS && ok && path.Ext(name) == ".css"
+	}
+	for name := range previous {
+		if _, ok := next[name]; !ok {
+			changed = append(changed, name)
+			onlyCSS = false
+		}
+	}
+	slices.Sort(changed)
+	return changed, onlyCSS
+}
+
+// devMessage is sent to the pages connected to a DevServer, as JSON, for
+// autoreload.js to act on.
+type devMessage struct {
+	// Type is "reload", "css" or "error".
+	Type string `json:"type"`
+	// Paths are the stylesheets to swap for a "css" message.
+	Paths []string `json:"paths,omitempty"`
+	// File, Stage and Message describe the failure for an "error" message.
+	File    string `json:"file,omitempty"`
+	Stage   string `json:"stage,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// newDevErrorMessage describes a failed build, using the details of a
+// *BuildError when there is one.
+func newDevErrorMessage(err error) devMessage {
+	message := devMessage{Type: "error", Message: err.Error()}
+
+	var buildErr *BuildError
+	if errors.As(err, &buildErr) {
+		message.File = buildErr.Path
+		message.Stage = buildErr.Transformer
+		if buildErr.Stage != "" {
+			message.Stage = buildErr.Stage + " (" + buildErr.Transformer + ")"
+		}
+		message.Message = buildErr.Err.Error()
+	}
+	return message
+}
+
+// broadcast sends a message to every connected page, dropping those that can't
+// be written to. The caller must hold s.mu.
+func (s *DevServer) broadcast(message devMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	for conn := range s.clients {
+		if err := writeWebSocketText(conn, data); err != nil {
+			conn.Close()
+			delete(s.clients, conn)
+		}
+	}
+}
+
+func (s *DevServer) logf(format string, args ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (s *DevServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	webSocketPath := s.WebSocketPath
+	if webSocketPath == "" {
+		webSocketPath = "/ws"
+	}
+	if r.URL.Path == webSocketPath {
+		s.serveWebSocket(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	handler, err := s.handler, s.err
+	s.mu.Unlock()
+
+	if handler == nil {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "building", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	handler.ServeHTTP(w, r)
+}
+
+// webSocketGUID is appended to the client's key to accept a WebSocket
+// connection, see RFC 6455 section 1.3.
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// serveWebSocket accepts a WebSocket connection and holds it open to send
+// messages to, discarding anything the client sends.
+func (s *DevServer) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accept := sha1.Sum([]byte(key + webSocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	if s.clients == nil {
+		s.clients = map[net.Conn]bool{}
+	}
+	s.clients[conn] = true
+	// a page loaded from the last good build should still see the failure
+	if s.err != nil {
+		if data, err := json.Marshal(newDevErrorMessage(s.err)); err == nil {
+			writeWebSocketText(conn, data)
+		}
+	}
+	s.mu.Unlock()
+
+	go func() {
+		io.Copy(io.Discard, rw)
+		s.mu.Lock()
+		delete(s.clients, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+}
+
+// writeWebSocketText sends data to conn in a single unmasked text frame.
+func writeWebSocketText(conn net.Conn, data []byte) error {
+	frame := []byte{0x81}
+	switch {
+	case len(data) < 126:
+		frame = append(frame, byte(len(data)))
+	case len(data) <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(data)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(data)))
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, err := conn.Write(append(frame, data...))
+	return err
+}
+
+// sourceSignature summarises the name, size, mode and modification time of
+// every file in fsys that rules don't ignore, so comparing signatures tells
+// whether anything changed.
+func sourceSignature(fsys fs.FS, rules ignoreRules) ([]byte, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && rules.ignored(name, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%v\x00%d\n", name, info.Size(), info.Mode(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
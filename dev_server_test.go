@@ -0,0 +1,413 @@
+package sitetools
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dialWebSocket opens a WebSocket connection to the server at url.
+func dialWebSocket(t *testing.T, url, path string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	io.WriteString(conn, "GET "+path+" HTTP/1.1\r\n"+
+		"Host: localhost\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", accept)
+	}
+	return conn, reader
+}
+
+// readDevMessage reads a single text frame sent by a DevServer.
+func readDevMessage(t *testing.T, conn net.Conn, reader *bufio.Reader) devMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	if header[0] != 0x81 {
+		t.Fatalf("expected text frame, got %x", header[0])
+	}
+
+	length := int(header[1])
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(reader, ext)
+		length = int(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(reader, ext)
+		length = int(binary.BigEndian.Uint64(ext))
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+
+	var message devMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		t.Fatalf("invalid message %q: %v", payload, err)
+	}
+	return message
+}
+
+func getBody(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestDevServer(t *testing.T, fail *atomic.Bool) (*DevServer, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "index.html", "v1")
+
+	server := &DevServer{
+		Source:   os.DirFS(dir),
+		Interval: 10 * time.Millisecond,
+		ErrorLog: log.New(io.Discard, "", 0),
+		Build: func(ctx context.Context) (*Build, error) {
+			if fail != nil && fail.Load() {
+				return nil, errors.New("broken")
+			}
+			build := &Build{}
+			if err := build.FromDir(os.DirFS(dir), "."); err != nil {
+				return nil, err
+			}
+			return build, nil
+		},
+	}
+	return server, dir
+}
+
+func TestDevServer(t *testing.T) {
+	server, dir := newTestDevServer(t, nil)
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- server.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() returned error: %v", err)
+		}
+	}()
+
+	waitFor(t, "initial build", func() bool {
+		_, body := getBody(t, ts.URL+"/")
+		return body == "v1"
+	})
+
+	conn, reader := dialWebSocket(t, ts.URL, "/ws")
+
+	writeTestFile(t, dir, "index.html", "version 2")
+	writeTestFile(t, dir, "posts/first.html", "first")
+
+	// pages are told to reload once the rebuild is done
+	if message := readDevMessage(t, conn, reader); message.Type != "reload" {
+		t.Errorf("expected reload message, got %+v", message)
+	}
+
+	// the two writes may have been picked up by separate rebuilds
+	waitFor(t, "rebuild", func() bool {
+		_, index := getBody(t, ts.URL+"/index.html")
+		_, post := getBody(t, ts.URL+"/posts/first.html")
+		return index == "version 2" && post == "first"
+	})
+	if status, _ := getBody(t, ts.URL+"/missing.html"); status != http.StatusNotFound {
+		t.Errorf("GET /missing.html status = %d, want 404", status)
+	}
+}
+
+func TestDevServer_BuildError(t *testing.T) {
+	var fail atomic.Bool
+	server, dir := newTestDevServer(t, &fail)
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- server.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor(t, "initial build", func() bool {
+		_, body := getBody(t, ts.URL+"/")
+		return body == "v1"
+	})
+
+	// the last good build is served while the build is broken
+	fail.Store(true)
+	writeTestFile(t, dir, "index.html", "v2")
+	waitFor(t, "failed build", func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.err != nil
+	})
+	if _, body := getBody(t, ts.URL+"/"); body != "v1" {
+		t.Errorf("GET / = %q, want the last good build", body)
+	}
+
+	fail.Store(false)
+	writeTestFile(t, dir, "index.html", "v3")
+	waitFor(t, "fixed build", func() bool {
+		_, body := getBody(t, ts.URL+"/")
+		return body == "v3"
+	})
+}
+
+func TestDevServer_Messages(t *testing.T) {
+	var fail atomic.Bool
+	server, dir := newTestDevServer(t, &fail)
+	writeTestFile(t, dir, "style.css", "body { color: red }")
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- server.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor(t, "initial build", func() bool {
+		_, body := getBody(t, ts.URL+"/")
+		return body == "v1"
+	})
+	conn, reader := dialWebSocket(t, ts.URL, "/ws")
+
+	// changing only a stylesheet swaps it in place
+	writeTestFile(t, dir, "style.css", "body { color: blue }")
+	message := readDevMessage(t, conn, reader)
+	if message.Type != "css" || !slices.Equal(message.Paths, []string{"/style.css"}) {
+		t.Errorf("expected css message for /style.css, got %+v", message)
+	}
+
+	// a failed build is reported, including to pages connecting afterwards
+	fail.Store(true)
+	writeTestFile(t, dir, "index.html", "v2")
+	message = readDevMessage(t, conn, reader)
+	if message.Type != "error" || message.Message != "broken" {
+		t.Errorf("expected error message, got %+v", message)
+	}
+	lateConn, lateReader := dialWebSocket(t, ts.URL, "/ws")
+	if message := readDevMessage(t, lateConn, lateReader); message.Type != "error" {
+		t.Errorf("expected error message on connect, got %+v", message)
+	}
+
+	// fixing the build reloads the page, even for a stylesheet change
+	fail.Store(false)
+	writeTestFile(t, dir, "style.css", "body { color: green }")
+	if message := readDevMessage(t, conn, reader); message.Type != "reload" {
+		t.Errorf("expected reload message, got %+v", message)
+	}
+}
+
+func TestNewDevErrorMessage(t *testing.T) {
+	err := fmt.Errorf("stage render: %w", &BuildError{
+		Path:        "/posts/first.md",
+		Transformer: "sitetools.MarkdownTransformer",
+		Stage:       "render",
+		Err:         errors.New("bad markdown"),
+	})
+
+	expected := devMessage{
+		Type:    "error",
+		File:    "/posts/first.md",
+		Stage:   "render (sitetools.MarkdownTransformer)",
+		Message: "bad markdown",
+	}
+	if message := newDevErrorMessage(err); !reflect.DeepEqual(message, expected) {
+		t.Errorf("newDevErrorMessage() = %+v, want %+v", message, expected)
+	}
+
+	if message := newDevErrorMessage(errors.New("plain")); message.Message != "plain" || message.File != "" {
+		t.Errorf("newDevErrorMessage() for a plain error = %+v", message)
+	}
+}
+
+func TestDevServer_CancelsObsoleteBuilds(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "index.html", "v1")
+
+	var started, cancelled atomic.Int32
+	server := &DevServer{
+		Source:   os.DirFS(dir),
+		Interval: 10 * time.Millisecond,
+		Build: func(ctx context.Context) (*Build, error) {
+			if started.Add(1) == 1 {
+				<-ctx.Done()
+				cancelled.Add(1)
+				return nil, ctx.Err()
+			}
+			return &Build{Assets: Assets{newTestAsset("/index.html", "done", nil)}}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- server.Run(ctx) }()
+
+	waitFor(t, "first build", func() bool { return started.Load() == 1 })
+	writeTestFile(t, dir, "index.html", "version 2")
+	waitFor(t, "second build", func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.assets != nil
+	})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() returned error: %v", err)
+	}
+	if cancelled.Load() != 1 {
+		t.Errorf("expected the obsolete build to be cancelled")
+	}
+}
+
+func TestDevServer_IgnoresSource(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "index.html", "v1")
+	writeTestFile(t, dir, ".git/index", "v1")
+
+	var builds atomic.Int32
+	server := &DevServer{
+		Source:   os.DirFS(dir),
+		Interval: 10 * time.Millisecond,
+		Build: func(ctx context.Context) (*Build, error) {
+			builds.Add(1)
+			return &Build{}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- server.Run(ctx) }()
+
+	waitFor(t, "first build", func() bool { return builds.Load() == 1 })
+	writeTestFile(t, dir, ".git/index", "version 2")
+	writeTestFile(t, dir, ".git/objects/ab/cdef", "object")
+	time.Sleep(100 * time.Millisecond)
+	if n := builds.Load(); n != 1 {
+		t.Errorf("changes in .git caused %d rebuilds, want none", n-1)
+	}
+
+	writeTestFile(t, dir, "index.html", "version 2")
+	waitFor(t, "rebuild", func() bool { return builds.Load() == 2 })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() returned error: %v", err)
+	}
+}
+
+func TestDevServer_ListenAndServeError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	server, _ := newTestDevServer(t, nil)
+	done := make(chan error)
+	go func() { done <- server.ListenAndServe(context.Background(), listener.Addr().String()) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected error for an address in use, got nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe() kept running after failing to listen")
+	}
+}
+
+func TestDevServer_Errors(t *testing.T) {
+	if err := (&DevServer{}).Run(context.Background()); err == nil {
+		t.Error("expected error without Source, got nil")
+	}
+	if err := (&DevServer{Source: os.DirFS(t.TempDir())}).Run(context.Background()); err == nil {
+		t.Error("expected error without Build, got nil")
+	}
+
+	server := &DevServer{}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("plain request to WebSocketPath status = %d, want 400", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("request before the first build status = %d, want 503", rec.Code)
+	}
+}
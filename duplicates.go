@@ -0,0 +1,90 @@
+package sitetools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy is what Deduplicate does with assets that have the same Path.
+type DuplicatePolicy int
+
+const (
+	// DuplicateError fails with a *DuplicatePathError for each Path.
+	DuplicateError DuplicatePolicy = iota
+	// DuplicateKeepFirst keeps the first asset with each Path.
+	DuplicateKeepFirst
+	// DuplicateKeepLast keeps the last asset with each Path, which is the one
+	// Write would leave on disk.
+	DuplicateKeepLast
+)
+
+// DuplicatePathError reports assets that would be written to the same Path.
+type DuplicatePathError struct {
+	Path string
+	// Sources are where the assets came from, in order: their "SourcePath" meta,
+	// or their Path if they have none.
+	Sources []string
+}
+
+func (e *DuplicatePathError) Error() string {
+	return fmt.Sprintf("duplicate output path %s from %s", e.Path, strings.Join(e.Sources, ", "))
+}
+
+// Deduplicate finds assets that would be written to the same Path, such as
+// about.md and about.html once both are rendered, and resolves them according
+// to policy. Run it after the transformers that rename assets.
+//
+// With DuplicateError, the assets are left as they are, and every collision is
+// returned as a *DuplicatePathError. Otherwise, only one asset is kept for each
+// Path, in its original position.
+func (assets *Assets) Deduplicate(policy DuplicatePolicy) error {
+	var order []string
+	groups := map[string][]int{}
+	for i, asset := range *assets {
+		key := cleanPath(asset.Path)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	switch policy {
+	case DuplicateError:
+		var errs []error
+		for _, key := range order {
+			if len(groups[key]) < 2 {
+				continue
+			}
+			dupErr := &DuplicatePathError{Path: key}
+			for _, i := range groups[key] {
+				dupErr.Sources = append(dupErr.Sources, sourceID((*assets)[i]))
+			}
+			errs = append(errs, dupErr)
+		}
+		return errors.Join(errs...)
+
+	case DuplicateKeepFirst, DuplicateKeepLast:
+		keep := make([]bool, len(*assets))
+		for _, indexes := range groups {
+			if policy == DuplicateKeepFirst {
+				keep[indexes[0]] = true
+			} else {
+				keep[indexes[len(indexes)-1]] = true
+			}
+		}
+
+		kept := (*assets)[:0]
+		for i, asset := range *assets {
+			if keep[i] {
+				kept = append(kept, asset)
+			}
+		}
+		clear((*assets)[len(kept):])
+		*assets = kept
+		return nil
+
+	default:
+		return fmt.Errorf("unknown duplicate policy %d", policy)
+	}
+}
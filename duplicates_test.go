@@ -0,0 +1,79 @@
+package sitetools
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func newDuplicateTestAssets() Assets {
+	return Assets{
+		newTestAsset("/about.html", "from markdown", map[string]any{"SourcePath": "about.md"}),
+		newTestAsset("/index.html", "home", map[string]any{"SourcePath": "index.html"}),
+		newTestAsset("about.html", "from html", map[string]any{"SourcePath": "about.html"}),
+		newTestAsset("/img/a.webp", "png", map[string]any{"SourcePath": "img/a.png"}),
+		newTestAsset("/img/a.webp", "jpg", nil),
+	}
+}
+
+func TestAssets_Deduplicate_Error(t *testing.T) {
+	assets := newDuplicateTestAssets()
+
+	err := assets.Deduplicate(DuplicateError)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	var dupErr *DuplicatePathError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected a *DuplicatePathError, got %T", err)
+	}
+	if dupErr.Path != "/about.html" || !slices.Equal(dupErr.Sources, []string{"about.md", "about.html"}) {
+		t.Errorf("first error = %+v", dupErr)
+	}
+
+	expected := "duplicate output path /about.html from about.md, about.html\n" +
+		"duplicate output path /img/a.webp from img/a.png, /img/a.webp"
+	if err.Error() != expected {
+		t.Errorf("error = %q, want %q", err, expected)
+	}
+
+	if len(assets) != 5 {
+		t.Errorf("assets were modified, got %d", len(assets))
+	}
+
+	unique := Assets{newTestAsset("/a.html", "", nil), newTestAsset("/b.html", "", nil)}
+	if err := unique.Deduplicate(DuplicateError); err != nil {
+		t.Errorf("unexpected error for unique paths: %v", err)
+	}
+}
+
+func TestAssets_Deduplicate_Keep(t *testing.T) {
+	tests := []struct {
+		policy   DuplicatePolicy
+		expected []string
+	}{
+		{DuplicateKeepFirst, []string{"from markdown", "home", "png"}},
+		{DuplicateKeepLast, []string{"home", "from html", "jpg"}},
+	}
+
+	for _, tt := range tests {
+		assets := newDuplicateTestAssets()
+		if err := assets.Deduplicate(tt.policy); err != nil {
+			t.Fatalf("Deduplicate(%d) returned error: %v", tt.policy, err)
+		}
+
+		var got []string
+		for _, asset := range assets {
+			got = append(got, string(asset.Data))
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("Deduplicate(%d) kept %v, want %v", tt.policy, got, tt.expected)
+		}
+	}
+
+	assets := newDuplicateTestAssets()
+	if err := assets.Deduplicate(DuplicatePolicy(42)); err == nil {
+		t.Error("expected error for unknown policy, got nil")
+	}
+}
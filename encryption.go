@@ -2,19 +2,22 @@ package sitetools
 
 import (
 	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	_ "embed"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 
 	"filippo.io/age"
-	"golang.org/x/crypto/pbkdf2"
 )
 
 //go:embed assets/encryption_decrypt_script.js
@@ -55,6 +58,14 @@ type PreambleProvider interface {
 	PreambleScript() []byte
 }
 
+// ContextEncryptionAlgorithm is an optional interface that EncryptionAlgorithm
+// implementations may also implement to stop early when a context is
+// cancelled. EncryptionTransformer.TransformContext uses EncryptContext in
+// place of Encrypt for algorithms that implement it.
+type ContextEncryptionAlgorithm interface {
+	EncryptContext(ctx context.Context, data []byte) (ciphertext []byte, replacements map[string]string, err error)
+}
+
 // EncryptionTransformer encrypts page content with client-side decryption.
 //
 // All algorithm-specific configuration lives on the Algorithm value (see
@@ -77,6 +88,16 @@ type EncryptionTransformer struct {
 }
 
 func (t EncryptionTransformer) Transform(asset *Asset) error {
+	return t.TransformContext(context.Background(), asset)
+}
+
+// TransformContext encrypts the asset, stopping with the context's error if ctx
+// is cancelled. How soon it stops depends on the algorithm: AESGCMEncryption
+// checks ctx throughout its (deliberately slow) key derivation, while
+// AgeEncryption only checks it once its scrypt work is done, as age can't be
+// interrupted. Algorithms not implementing ContextEncryptionAlgorithm run to
+// completion and their result is discarded.
+func (t EncryptionTransformer) TransformContext(ctx context.Context, asset *Asset) error {
 	if t.Template == nil {
 		return fmt.Errorf("encryption template is required")
 	}
@@ -110,7 +131,20 @@ func (t EncryptionTransformer) Transform(asset *Asset) error {
 		}
 	}
 
-	ciphertext, algoReplacements, err := t.Algorithm.Encrypt(asset.Data)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var ciphertext []byte
+	var algoReplacements map[string]string
+	var err error
+	if ca, ok := t.Algorithm.(ContextEncryptionAlgorithm); ok {
+		ciphertext, algoReplacements, err = ca.EncryptContext(ctx, asset.Data)
+	} else {
+		ciphertext, algoReplacements, err = t.Algorithm.Encrypt(asset.Data)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return fmt.Errorf("failed to encrypt asset: %w", err)
 	}
@@ -225,6 +259,12 @@ type AESGCMEncryption struct {
 }
 
 func (a *AESGCMEncryption) Encrypt(data []byte) ([]byte, map[string]string, error) {
+	return a.EncryptContext(context.Background(), data)
+}
+
+// EncryptContext is like Encrypt, but stops with the context's error if ctx is
+// cancelled while the key is being derived.
+func (a *AESGCMEncryption) EncryptContext(ctx context.Context, data []byte) ([]byte, map[string]string, error) {
 	if a.Password == "" {
 		return nil, nil, fmt.Errorf("password is required for AES-GCM encryption")
 	}
@@ -232,7 +272,7 @@ func (a *AESGCMEncryption) Encrypt(data []byte) ([]byte, map[string]string, erro
 	if iterations == 0 {
 		iterations = 600000
 	}
-	ciphertext, salt, err := encryptAESGCM(data, a.Password, iterations, a.Salt)
+	ciphertext, salt, err := encryptAESGCM(ctx, data, a.Password, iterations, a.Salt)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -245,7 +285,7 @@ func (a *AESGCMEncryption) Encrypt(data []byte) ([]byte, map[string]string, erro
 func (a *AESGCMEncryption) ScriptTemplate() []byte { return decryptScriptTemplate }
 
 // encryptAESGCM encrypts data using AES-GCM with PBKDF2 key derivation.
-func encryptAESGCM(data []byte, password string, iterations int, salt []byte) ([]byte, []byte, error) {
+func encryptAESGCM(ctx context.Context, data []byte, password string, iterations int, salt []byte) ([]byte, []byte, error) {
 	if len(salt) == 0 {
 		var err error
 		salt, err = RandomSalt()
@@ -256,7 +296,10 @@ func encryptAESGCM(data []byte, password string, iterations int, salt []byte) ([
 		salt = append([]byte(nil), salt...)
 	}
 
-	key := pbkdf2.Key([]byte(password), salt, iterations, 32, sha256.New)
+	key, err := deriveKey(ctx, []byte(password), salt, iterations, 32)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -275,6 +318,47 @@ func encryptAESGCM(data []byte, password string, iterations int, salt []byte) ([
 	return ciphertext, salt, nil
 }
 
+// pbkdf2CheckEvery is how many PBKDF2 iterations deriveKey runs between
+// checks of its context.
+const pbkdf2CheckEvery = 10000
+
+// deriveKey derives a keyLen byte key from password with PBKDF2-HMAC-SHA256
+// (RFC 8018), like pbkdf2.Key, but stops with the context's error if ctx is
+// cancelled partway through, as hundreds of thousands of iterations take a
+// noticeable time.
+func deriveKey(ctx context.Context, password, salt []byte, iterations, keyLen int) ([]byte, error) {
+	prf := hmac.New(sha256.New, password)
+	key := make([]byte, 0, keyLen)
+
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u := prf.Sum(nil)
+		t := slices.Clone(u)
+
+		for i := 1; i < iterations; i++ {
+			if i%pbkdf2CheckEvery == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return key[:keyLen], nil
+}
+
 // AgeEncryption encrypts content using the age file format (filippo.io/age).
 // Decryption in the browser is performed with a vendored copy of the typage
 // library (bundled into the page; no network access required at decrypt time).
@@ -289,6 +373,12 @@ type AgeEncryption struct {
 }
 
 func (a *AgeEncryption) Encrypt(data []byte) ([]byte, map[string]string, error) {
+	return a.EncryptContext(context.Background(), data)
+}
+
+// EncryptContext is like Encrypt, but stops with the context's error if ctx is
+// cancelled by the time the file key has been wrapped for the recipients.
+func (a *AgeEncryption) EncryptContext(ctx context.Context, data []byte) ([]byte, map[string]string, error) {
 	if a.Password == "" && len(a.Recipients) == 0 {
 		return nil, nil, fmt.Errorf("age encryption requires a Password and/or Recipients")
 	}
@@ -314,6 +404,9 @@ func (a *AgeEncryption) Encrypt(data []byte) ([]byte, map[string]string, error)
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	if _, err := w.Write(data); err != nil {
 		return nil, nil, err
 	}
@@ -2,9 +2,15 @@ package sitetools
 
 import (
 	"bytes"
+	"context"
+	"crypto/pbkdf2"
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"math"
 	"strings"
 	"testing"
+	"time"
 
 	"filippo.io/age"
 )
@@ -95,11 +101,11 @@ func TestEncryptAESGCM_UsesRandomSaltWhenEmpty(t *testing.T) {
 	password := "test-password"
 	iterations := 100000
 
-	_, salt1, err := encryptAESGCM(data, password, iterations, nil)
+	_, salt1, err := encryptAESGCM(context.Background(), data, password, iterations, nil)
 	if err != nil {
 		t.Fatalf("encryption failed: %v", err)
 	}
-	_, salt2, err := encryptAESGCM(data, password, iterations, nil)
+	_, salt2, err := encryptAESGCM(context.Background(), data, password, iterations, nil)
 	if err != nil {
 		t.Fatalf("encryption failed: %v", err)
 	}
@@ -363,3 +369,88 @@ func TestEncryptionTransformer_DerivedKeyCachingHooksPresent(t *testing.T) {
 		t.Errorf("expected derived key cache setItem call")
 	}
 }
+
+// cancellingEncryption cancels the context it is given partway through, as if
+// the build were cancelled while it was encrypting.
+type cancellingEncryption struct {
+	cancel context.CancelFunc
+}
+
+func (c cancellingEncryption) Encrypt(data []byte) ([]byte, map[string]string, error) {
+	c.cancel()
+	return data, nil, nil
+}
+
+func (c cancellingEncryption) ScriptTemplate() []byte { return decryptScriptTemplate }
+
+func TestEncryptionTransformer_StopsWhenContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transformer := EncryptionTransformer{
+		Template:  newEncryptionTemplate(),
+		Algorithm: cancellingEncryption{cancel: cancel},
+	}
+
+	asset := &Asset{
+		Path: "/test.html",
+		Data: []byte("<html><body>Test content</body></html>"),
+	}
+
+	err := transformer.TransformContext(ctx, asset)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("TransformContext() = %v, want %v", err, context.Canceled)
+	}
+	if string(asset.Data) != "<html><body>Test content</body></html>" {
+		t.Errorf("asset was modified despite cancellation: %q", asset.Data)
+	}
+}
+
+func TestEncryptContext_StopsAfterKeyDerivation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	algorithms := []ContextEncryptionAlgorithm{
+		&AESGCMEncryption{Password: "secret", Iterations: 1},
+		&AgeEncryption{Password: "secret"},
+	}
+	for _, algorithm := range algorithms {
+		if _, _, err := algorithm.EncryptContext(ctx, []byte("data")); !errors.Is(err, context.Canceled) {
+			t.Errorf("%T.EncryptContext() = %v, want %v", algorithm, err, context.Canceled)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	salt := []byte("salt")
+	for _, keyLen := range []int{16, 32, 40} {
+		got, err := deriveKey(context.Background(), []byte("password"), salt, 4096, keyLen)
+		if err != nil {
+			t.Fatalf("deriveKey() returned error: %v", err)
+		}
+		want, err := pbkdf2.Key(sha256.New, "password", salt, 4096, keyLen)
+		if err != nil {
+			t.Fatalf("pbkdf2.Key() returned error: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("deriveKey() with keyLen %d = %x, want %x", keyLen, got, want)
+		}
+	}
+
+	// cancelling stops the derivation partway through, not after it
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := deriveKey(ctx, []byte("password"), salt, math.MaxInt32, 32)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("deriveKey() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("deriveKey() kept going after its context was cancelled")
+	}
+}
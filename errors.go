@@ -0,0 +1,60 @@
+package sitetools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildError records a transformer failing on an asset.
+type BuildError struct {
+	// Path is the asset's Path when the transformer was called.
+	Path string
+	// Transformer is the type of the transformer that failed, e.g. "sitetools.MinifyTransformer".
+	Transformer string
+	// Stage is the name of the Pipeline stage the transformer ran in, if any.
+	Stage string
+	Err   error
+}
+
+// newBuildError wraps err, unless it is already a *BuildError from a
+// transformer that runs others (e.g. ScopedTransformer), whose details about
+// the inner transformer are kept instead.
+func newBuildError(assetPath string, transformer Transformer, err error) *BuildError {
+	if buildErr, ok := err.(*BuildError); ok {
+		return buildErr
+	}
+	return &BuildError{
+		Path:        assetPath,
+		Transformer: fmt.Sprintf("%T", transformer),
+		Err:         err,
+	}
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Path, e.Transformer, e.Err)
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// BuildErrors is every failure from a transform that carried on past errors,
+// in the order they occurred. errors.Is and errors.As look through each of
+// them.
+type BuildErrors []*BuildError
+
+func (errs BuildErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (errs BuildErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
@@ -0,0 +1,43 @@
+package sitetools
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestBuildError(t *testing.T) {
+	err := newBuildError("/index.html", MinifyTransformer{}, fs.ErrInvalid)
+
+	if err.Error() != "/index.html: sitetools.MinifyTransformer: invalid argument" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if !errors.Is(err, fs.ErrInvalid) {
+		t.Error("errors.Is() did not find the wrapped error")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	errs := BuildErrors{
+		newBuildError("/a.html", MinifyTransformer{}, fs.ErrInvalid),
+		newBuildError("/b.html", &MockTransformer{}, fs.ErrPermission),
+	}
+
+	expected := "/a.html: sitetools.MinifyTransformer: invalid argument\n/b.html: *sitetools.MockTransformer: permission denied"
+	if errs.Error() != expected {
+		t.Errorf("Error() = %q, want %q", errs.Error(), expected)
+	}
+
+	var err error = errs
+	if !errors.Is(err, fs.ErrInvalid) || !errors.Is(err, fs.ErrPermission) {
+		t.Error("errors.Is() did not find every wrapped error")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Error("errors.Is() matched an error that was not wrapped")
+	}
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) || buildErr.Path != "/a.html" {
+		t.Errorf("errors.As() = %v, want the first BuildError", buildErr)
+	}
+}
@@ -1,6 +1,7 @@
 package sitetools
 
 import (
+	"context"
 	"mime"
 	"path"
 	"slices"
@@ -43,6 +44,53 @@ func (assets Assets) Filter(filters ...Filter) Assets {
 	return assets.Pop(filters...)
 }
 
+// ScopedTransformer runs its Transformers, in order, on the assets that match
+// all of its Filters and leaves other assets untouched. Create one with When.
+type ScopedTransformer struct {
+	Filters      []Filter
+	Transformers []Transformer
+}
+
+// When scopes transformers to the assets matching all of filters, so they can
+// be mixed with others in a single Assets.Transform call:
+//
+//	build.Assets.Transform(
+//		sitetools.MarkdownTransformer{},
+//		sitetools.When(sitetools.WithMeta("Protected")).Then(encryption),
+//	)
+func When(filters ...Filter) ScopedTransformer {
+	return ScopedTransformer{Filters: filters}
+}
+
+// Then returns a copy of the ScopedTransformer with transformers added.
+func (s ScopedTransformer) Then(transformers ...Transformer) ScopedTransformer {
+	s.Transformers = append(slices.Clip(s.Transformers), transformers...)
+	return s
+}
+
+func (s ScopedTransformer) Transform(asset *Asset) error {
+	return s.TransformContext(context.Background(), asset)
+}
+
+// TransformContext checks the filters once, before running any of the
+// transformers. A failure is reported as a *BuildError naming the transformer
+// within the scope that failed.
+func (s ScopedTransformer) TransformContext(ctx context.Context, asset *Asset) error {
+	for _, filter := range s.Filters {
+		if !filter(*asset) {
+			return nil
+		}
+	}
+
+	for _, transformer := range s.Transformers {
+		assetPath := asset.Path
+		if err := AdaptContext(transformer).TransformContext(ctx, asset); err != nil {
+			return newBuildError(assetPath, transformer, err)
+		}
+	}
+	return nil
+}
+
 func WithParentDir(parent string) Filter {
 	return func(asset Asset) bool {
 		dir := path.Dir(asset.Path)
@@ -1,6 +1,7 @@
 package sitetools
 
 import (
+	"errors"
 	"path"
 	"reflect"
 	"strings"
@@ -326,3 +327,73 @@ func TestWithoutMimeType_TopLevel(t *testing.T) {
 		t.Errorf("Expected /test/file3.png, got %s", filtered[0].Path)
 	}
 }
+
+func TestWhen(t *testing.T) {
+	assets := Assets{
+		&Asset{Path: "/public.html", Data: []byte("public"), Meta: map[string]any{}},
+		&Asset{Path: "/secret.html", Data: []byte("secret"), Meta: map[string]any{"Protected": true}},
+		&Asset{Path: "/secret.css", Data: []byte("secret"), Meta: map[string]any{"Protected": true}},
+	}
+
+	appendData := func(suffix string) Transformer {
+		return &MockTransformer{
+			TransformFunc: func(a *Asset) error {
+				a.Data = append(a.Data, suffix...)
+				return nil
+			},
+		}
+	}
+
+	err := assets.Transform(
+		appendData("-1"),
+		When(WithMeta("Protected"), WithExtensions(".html")).Then(appendData("-2"), appendData("-3")),
+		appendData("-4"),
+	)
+	if err != nil {
+		t.Fatalf("Transform() returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"/public.html": "public-1-4",
+		"/secret.html": "secret-1-2-3-4",
+		"/secret.css":  "secret-1-4",
+	}
+	for _, asset := range assets {
+		if string(asset.Data) != expected[asset.Path] {
+			t.Errorf("asset %s Data = %q, want %q", asset.Path, asset.Data, expected[asset.Path])
+		}
+	}
+}
+
+func TestWhen_ThenDoesNotShareTransformers(t *testing.T) {
+	base := When().Then(&MockTransformer{})
+	a := base.Then(&MockTransformer{})
+	b := base.Then(&MockTransformer{}, &MockTransformer{})
+
+	if len(base.Transformers) != 1 || len(a.Transformers) != 2 || len(b.Transformers) != 3 {
+		t.Errorf("transformer counts = %d, %d, %d, want 1, 2, 3", len(base.Transformers), len(a.Transformers), len(b.Transformers))
+	}
+	if a.Transformers[1] == b.Transformers[1] {
+		t.Error("Then() calls on the same ScopedTransformer share transformers")
+	}
+}
+
+func TestWhen_Error(t *testing.T) {
+	assets := Assets{&Asset{Path: "/broken.html", Data: []byte("{{ .Broken "), Meta: map[string]any{"Template": true}}}
+
+	err := assets.Transform(When(WithMeta("Template")).Then(&MockTransformer{}, TemplateTransformer{}))
+	if err == nil {
+		t.Fatal("Transform() did not return an error")
+	}
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("Transform() error %T is not a *BuildError", err)
+	}
+	if buildErr.Path != "/broken.html" || buildErr.Transformer != "sitetools.TemplateTransformer" {
+		t.Errorf("BuildError = %+v, want the inner transformer", buildErr)
+	}
+	if errors.As(buildErr.Err, new(*BuildError)) {
+		t.Errorf("BuildError is nested: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package sitetools
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"text/template"
+)
+
+// GeneratePages adds one asset to the build for each record in data, a YAML,
+// JSON, TOML or CSV asset holding a list of records. As a TOML document is a
+// table, a table with a single key holding the list is accepted too, e.g. one
+// [[products]] array of tables. Each generated asset
+// starts as a copy of tmpl with the record's fields merged over its meta, so it
+// can be rendered later in the pipeline by TemplateTransformer or
+// WrapperTemplateTransformer like any other page.
+//
+// The Path of each generated asset is pathPattern executed as a text/template
+// against the record, e.g. "/products/{{.slug}}.html". Referencing a field the
+// record does not have is an error.
+//
+// The data and template are recorded in the "Dependencies" meta of each page,
+// see Build.Affected.
+func (build *Build) GeneratePages(data *Asset, tmpl *Asset, pathPattern string) error {
+	if data == nil {
+		return fmt.Errorf("data asset is required")
+	}
+	if tmpl == nil {
+		return fmt.Errorf("page template is required")
+	}
+
+	pathTemplate, err := template.New("path").Option("missingkey=error").Parse(pathPattern)
+	if err != nil {
+		return fmt.Errorf("invalid path pattern: %w", err)
+	}
+
+	parsed, err := parseData(*data)
+	if err != nil {
+		return err
+	}
+	records, err := dataRecords(parsed)
+	if err != nil {
+		return fmt.Errorf("issue in asset %s: %w", data.Path, err)
+	}
+
+	pages := make(Assets, 0, len(records))
+	for i, record := range records {
+		buf := &bytes.Buffer{}
+		if err := pathTemplate.Execute(buf, record); err != nil {
+			return fmt.Errorf("issue in asset %s: record %d: %w", data.Path, i, err)
+		}
+
+		meta := map[string]any{}
+		maps.Copy(meta, tmpl.Meta)
+		maps.Copy(meta, record)
+		// the page is not loaded from the template's source, but built from it
+		delete(meta, "SourcePath")
+		delete(meta, "Dependencies")
+
+		page := &Asset{
+			Path: cleanPath(buf.String()),
+			Data: slices.Clone(tmpl.Data),
+			Meta: meta,
+		}
+		addDependencies(page, data, tmpl)
+		pages = append(pages, page)
+	}
+
+	build.Assets = append(build.Assets, pages...)
+	return nil
+}
+
+// dataRecords returns parsed data as a list of records, or the list under the
+// only key of a table.
+func dataRecords(parsed any) ([]map[string]any, error) {
+	if table, ok := parsed.(map[string]any); ok && len(table) == 1 {
+		for _, list := range table {
+			switch list.(type) {
+			case []map[string]any, []any:
+				parsed = list
+			}
+		}
+	}
+
+	switch v := parsed.(type) {
+	case []map[string]any:
+		return v, nil
+	case []any:
+		records := make([]map[string]any, len(v))
+		for i, item := range v {
+			record, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("record %d is not a map", i)
+			}
+			records[i] = record
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("data must be a list of records")
+	}
+}
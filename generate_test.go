@@ -0,0 +1,113 @@
+package sitetools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePages(t *testing.T) {
+	tests := []struct {
+		name string
+		data *Asset
+	}{
+		{
+			name: "csv",
+			data: newTestAsset("/products.csv", "slug,name\nwidget,Widget\ngadget,Gadget\n", nil),
+		},
+		{
+			name: "json",
+			data: newTestAsset("/products.json", `[{"slug": "widget", "name": "Widget"}, {"slug": "gadget", "name": "Gadget"}]`, nil),
+		},
+		{
+			name: "yaml",
+			data: newTestAsset("/products.yaml", "- slug: widget\n  name: Widget\n- slug: gadget\n  name: Gadget\n", nil),
+		},
+		{
+			name: "toml",
+			data: newTestAsset("/products.toml", "[[products]]\nslug = \"widget\"\nname = \"Widget\"\n\n[[products]]\nslug = \"gadget\"\nname = \"Gadget\"\n", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := newTestAsset("/templates/product.html", "<h1>{{ .name }}</h1><p>{{ .Section }}</p>", map[string]any{"Section": "Products"})
+			build := &Build{Assets: Assets{newTestAsset("/index.html", "index", map[string]any{})}}
+
+			if err := build.GeneratePages(tt.data, tmpl, "products/{{.slug}}.html"); err != nil {
+				t.Fatalf("GeneratePages() returned error: %v", err)
+			}
+
+			if len(build.Assets) != 3 {
+				t.Fatalf("expected 3 assets, got %d", len(build.Assets))
+			}
+
+			if err := build.Transform(TemplateTransformer{}); err != nil {
+				t.Fatalf("Transform() returned error: %v", err)
+			}
+
+			expected := map[string]string{
+				"/products/widget.html": "<h1>Widget</h1><p>Products</p>",
+				"/products/gadget.html": "<h1>Gadget</h1><p>Products</p>",
+			}
+			for _, page := range build.Assets[1:] {
+				if want, ok := expected[page.Path]; !ok {
+					t.Errorf("unexpected page %s", page.Path)
+				} else if string(page.Data) != want {
+					t.Errorf("page %s Data = %q, want %q", page.Path, page.Data, want)
+				}
+				if page.Meta["Section"] != "Products" {
+					t.Errorf("page %s Meta[\"Section\"] = %v, want Products", page.Path, page.Meta["Section"])
+				}
+			}
+
+			// the template itself is left untouched
+			if string(tmpl.Data) != "<h1>{{ .name }}</h1><p>{{ .Section }}</p>" {
+				t.Errorf("template Data was modified: %q", tmpl.Data)
+			}
+			if len(tmpl.Meta) != 1 {
+				t.Errorf("template Meta was modified: %v", tmpl.Meta)
+			}
+
+			if err := build.AddSitemap("https://test.com"); err != nil {
+				t.Fatalf("AddSitemap() returned error: %v", err)
+			}
+			sitemap := string(build.Filter(WithPath("/sitemap.xml"))[0].Data)
+			if !strings.Contains(sitemap, "https://test.com/products/widget.html") {
+				t.Errorf("sitemap is missing generated page:\n%s", sitemap)
+			}
+		})
+	}
+}
+
+func TestGeneratePages_Errors(t *testing.T) {
+	tmpl := newTestAsset("/product.html", "", nil)
+	records := newTestAsset("/products.json", `[{"slug": "widget"}]`, nil)
+
+	tests := []struct {
+		name    string
+		data    *Asset
+		tmpl    *Asset
+		pattern string
+	}{
+		{"nil data", nil, tmpl, "/{{.slug}}.html"},
+		{"nil template", records, nil, "/{{.slug}}.html"},
+		{"invalid pattern", records, tmpl, "/{{.slug"},
+		{"missing field", records, tmpl, "/{{.name}}.html"},
+		{"invalid data", newTestAsset("/products.json", `[`, nil), tmpl, "/{{.slug}}.html"},
+		{"not a list", newTestAsset("/products.json", `{"slug": "widget"}`, nil), tmpl, "/{{.slug}}.html"},
+		{"not a list of records", newTestAsset("/products.json", `["widget"]`, nil), tmpl, "/{{.slug}}.html"},
+		{"several lists", newTestAsset("/products.toml", "[[products]]\nslug = \"widget\"\n[[other]]\nslug = \"gadget\"\n", nil), tmpl, "/{{.slug}}.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := &Build{}
+			if err := build.GeneratePages(tt.data, tt.tmpl, tt.pattern); err == nil {
+				t.Error("expected error, got nil")
+			}
+			if len(build.Assets) != 0 {
+				t.Errorf("expected no assets, got %d", len(build.Assets))
+			}
+		})
+	}
+}
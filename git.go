@@ -0,0 +1,256 @@
+package sitetools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FromGit loads the assets under root from the tree of ref (a commit, branch
+// or tag) in the local repository at repoPath, without needing a checked out
+// working tree. The repository may be bare. Paths are relative to the
+// repository root, so use "." or "" to load the whole tree. As Git does not
+// track file timestamps, "ModTime" is the time of the commit.
+func (build *Build) FromGit(repoPath, ref, root string) error {
+	if root == "" {
+		root = "."
+	}
+	root = path.Clean(root)
+
+	tree, err := gitTree(repoPath, ref, root)
+	if err != nil {
+		return err
+	}
+
+	assets, err := build.walkDir(newAssetIndex(tree), root)
+	if err != nil {
+		return err
+	}
+
+	build.Assets = append(build.Assets, assets...)
+	return nil
+}
+
+// git runs a git command against the repository at repoPath and returns its
+// standard output.
+func git(repoPath string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", repoPath}, args...)...)
+	cmd.Stdin = stdin
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// gitTree reads the blobs under root in the tree of ref into memory, as assets
+// named by their path in the repository.
+func gitTree(repoPath, ref, root string) (Assets, error) {
+	commit, err := git(repoPath, nil, "rev-parse", "--verify", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	commitHash := strings.TrimSpace(string(commit))
+
+	// files in a tree have no timestamps of their own, so use the commit's
+	commitTime, err := git(repoPath, nil, "log", "-1", "--format=%ct", commitHash)
+	if err != nil {
+		return nil, err
+	}
+	unix, err := strconv.ParseInt(strings.TrimSpace(string(commitTime)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected git log output: %q", commitTime)
+	}
+	modTime := time.Unix(unix, 0).UTC()
+
+	listing, err := git(repoPath, nil, "ls-tree", "-r", "-z", "--full-tree", commitHash, "--", root)
+	if err != nil {
+		return nil, err
+	}
+
+	type blob struct {
+		name string
+		mode fs.FileMode
+	}
+
+	var blobs []blob
+	var objects bytes.Buffer
+	for entry := range bytes.SplitSeq(listing, []byte{0}) {
+		if len(entry) == 0 {
+			continue
+		}
+
+		// <mode> SP <type> SP <object> TAB <file>
+		meta, name, ok := bytes.Cut(entry, []byte{'\t'})
+		if !ok {
+			return nil, fmt.Errorf("unexpected git ls-tree output: %q", entry)
+		}
+		fields := strings.Fields(string(meta))
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git ls-tree output: %q", entry)
+		}
+
+		// skip submodules (commit) and symlinks, which have no file content of their own
+		if fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+
+		mode := fs.FileMode(0644)
+		if fields[0] == "100755" {
+			mode = 0755
+		}
+
+		blobs = append(blobs, blob{name: string(name), mode: mode})
+		objects.WriteString(fields[2] + "\n")
+	}
+
+	contents, err := git(repoPath, &objects, "cat-file", "--batch")
+	if err != nil {
+		return nil, err
+	}
+
+	var tree Assets
+	reader := bufio.NewReader(bytes.NewReader(contents))
+	for _, b := range blobs {
+		// <object> SP <type> SP <size> LF <contents> LF
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("reading git object for %s: %w", b.name, err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git cat-file output for %s: %q", b.name, header)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected git cat-file output for %s: %q", b.name, header)
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("reading git object for %s: %w", b.name, err)
+		}
+		if _, err := reader.Discard(1); err != nil {
+			return nil, fmt.Errorf("reading git object for %s: %w", b.name, err)
+		}
+
+		tree = append(tree, &Asset{
+			Path: b.name,
+			Data: data,
+			Meta: map[string]any{"Mode": b.mode, "ModTime": modTime},
+		})
+	}
+
+	return tree, nil
+}
+
+// gitFileHistory is the commit history of a single file.
+type gitFileHistory struct {
+	lastModified string
+	created      string
+	author       string
+	commitHash   string
+}
+
+// AddGitHistory sets the "LastModified", "Created", "Author" and "CommitHash"
+// meta of assets loaded from root in the repository at repoPath, based on the
+// history of ref (HEAD if empty). Keys already present in an asset's meta are
+// left untouched, so hand-written front matter wins. Like repoPath, which may
+// be a subdirectory of the repository, root is a path on disk, so it is
+// relative to repoPath.
+//
+// Assets are matched by their "SourcePath" meta, either joined with root or on
+// its own, so it doesn't matter whether they were loaded from root or from
+// repoPath, nor whether a transformer (e.g. MarkdownTransformer) has renamed
+// them since. Assets without a "SourcePath" are matched by joining root with
+// their Path.
+func (build *Build) AddGitHistory(repoPath, ref, root string) error {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if root == "" {
+		root = "."
+	}
+	root = path.Clean(root)
+
+	out, err := git(repoPath, nil,
+		"-c", "core.quotePath=false",
+		// --relative lists paths relative to repoPath, as root is
+		"log", "--format=%x1e%H%x1f%cI%x1f%an", "--name-only", "--relative", "--end-of-options", ref, "--", root,
+	)
+	if err != nil {
+		return err
+	}
+
+	history := map[string]*gitFileHistory{}
+	for commit := range bytes.SplitSeq(out, []byte{0x1e}) {
+		header, files, _ := bytes.Cut(commit, []byte{'\n'})
+		fields := strings.Split(string(header), "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		hash, date, author := fields[0], fields[1], fields[2]
+
+		// commits are listed newest first
+		for file := range strings.SplitSeq(string(files), "\n") {
+			if file == "" {
+				continue
+			}
+			if h, ok := history[file]; ok {
+				h.created = date
+				continue
+			}
+			history[file] = &gitFileHistory{
+				lastModified: date,
+				created:      date,
+				author:       author,
+				commitHash:   hash,
+			}
+		}
+	}
+
+	for _, asset := range build.Assets {
+		candidates := []string{path.Join(root, asset.Path)}
+		if sourcePath, ok := asset.Meta["SourcePath"].(string); ok && sourcePath != "" {
+			candidates = []string{path.Join(root, sourcePath), path.Clean(sourcePath)}
+		}
+
+		var h *gitFileHistory
+		for _, candidate := range candidates {
+			if h = history[candidate]; h != nil {
+				break
+			}
+		}
+		if h == nil {
+			continue
+		}
+
+		if asset.Meta == nil {
+			asset.Meta = map[string]any{}
+		}
+
+		for key, value := range map[string]string{
+			"LastModified": h.lastModified,
+			"Created":      h.created,
+			"Author":       h.author,
+			"CommitHash":   h.commitHash,
+		} {
+			if _, ok := asset.Meta[key]; !ok {
+				asset.Meta[key] = value
+			}
+		}
+	}
+
+	return nil
+}
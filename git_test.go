@@ -0,0 +1,334 @@
+package sitetools
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRepo creates a git repository in a temporary directory, skipping the
+// test if git is not installed.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--quiet", "--initial-branch=main")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=Test Author",
+		"GIT_AUTHOR_EMAIL=author@example.com",
+		"GIT_COMMITTER_NAME=Test Committer",
+		"GIT_COMMITTER_EMAIL=committer@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func writeTestFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+
+	filePath := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("failed to create test dir: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+}
+
+func TestFromGit(t *testing.T) {
+	repo := newTestRepo(t)
+
+	writeTestFile(t, repo, "content/index.md", "v1")
+	writeTestFile(t, repo, "content/posts/first.md", "first")
+	writeTestFile(t, repo, "README.md", "not content")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "first")
+	runGit(t, repo, "tag", "v1.0.0")
+
+	writeTestFile(t, repo, "content/index.md", "v2")
+	writeTestFile(t, repo, "content/posts/second.md", "second")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "second")
+
+	// uncommitted changes must not be picked up
+	writeTestFile(t, repo, "content/index.md", "dirty")
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected map[string]string
+	}{
+		{
+			name: "tag",
+			ref:  "v1.0.0",
+			expected: map[string]string{
+				"/index.md":       "v1",
+				"/posts/first.md": "first",
+			},
+		},
+		{
+			name: "branch",
+			ref:  "main",
+			expected: map[string]string{
+				"/index.md":        "v2",
+				"/posts/first.md":  "first",
+				"/posts/second.md": "second",
+			},
+		},
+		{
+			name: "relative commit",
+			ref:  "HEAD~1",
+			expected: map[string]string{
+				"/index.md":       "v1",
+				"/posts/first.md": "first",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := &Build{}
+			if err := build.FromGit(repo, tt.ref, "content"); err != nil {
+				t.Fatalf("FromGit() returned error: %v", err)
+			}
+
+			if len(build.Assets) != len(tt.expected) {
+				t.Fatalf("expected %d assets, got %d", len(tt.expected), len(build.Assets))
+			}
+			for _, asset := range build.Assets {
+				want, ok := tt.expected[asset.Path]
+				if !ok {
+					t.Errorf("unexpected asset %s", asset.Path)
+					continue
+				}
+				if string(asset.Data) != want {
+					t.Errorf("asset %s data = %q, want %q", asset.Path, asset.Data, want)
+				}
+				if asset.Meta == nil {
+					t.Errorf("asset %s has nil Meta", asset.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestFromGit_BareRepository(t *testing.T) {
+	repo := newTestRepo(t)
+
+	writeTestFile(t, repo, "index.html", "<p>hello</p>")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "first")
+
+	bare := filepath.Join(t.TempDir(), "bare.git")
+	runGit(t, repo, "clone", "--quiet", "--bare", repo, bare)
+
+	build := &Build{}
+	if err := build.FromGit(bare, "main", ""); err != nil {
+		t.Fatalf("FromGit() returned error: %v", err)
+	}
+
+	if len(build.Assets) != 1 {
+		t.Fatalf("expected 1 asset, got %d", len(build.Assets))
+	}
+	if build.Assets[0].Path != "/index.html" {
+		t.Errorf("expected /index.html, got %s", build.Assets[0].Path)
+	}
+	if string(build.Assets[0].Data) != "<p>hello</p>" {
+		t.Errorf("expected '<p>hello</p>', got %q", build.Assets[0].Data)
+	}
+	if build.Assets[0].Meta["SourcePath"] != "index.html" {
+		t.Errorf("expected SourcePath index.html, got %v", build.Assets[0].Meta["SourcePath"])
+	}
+}
+
+func TestFromGit_ModTime(t *testing.T) {
+	repo := newTestRepo(t)
+
+	t.Setenv("GIT_COMMITTER_DATE", "2025-01-01T10:00:00Z")
+	writeTestFile(t, repo, "index.html", "v1")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "first")
+
+	build := &Build{}
+	if err := build.FromGit(repo, "HEAD", "."); err != nil {
+		t.Fatalf("FromGit() returned error: %v", err)
+	}
+
+	expected := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	if modTime, ok := build.Assets[0].Meta["ModTime"].(time.Time); !ok || !modTime.Equal(expected) {
+		t.Errorf("expected ModTime %v, got %v", expected, build.Assets[0].Meta["ModTime"])
+	}
+}
+
+func TestFromGit_Errors(t *testing.T) {
+	repo := newTestRepo(t)
+
+	writeTestFile(t, repo, "content/index.md", "hello")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "first")
+
+	if err := (&Build{}).FromGit(repo, "does-not-exist", "content"); err == nil {
+		t.Error("expected error for unknown ref, got nil")
+	}
+	if err := (&Build{}).FromGit(repo, "main", "missing"); err == nil {
+		t.Error("expected error for missing root, got nil")
+	}
+	if err := (&Build{}).FromGit(t.TempDir(), "main", ""); err == nil {
+		t.Error("expected error for non-repository, got nil")
+	}
+}
+
+func TestAddGitHistory(t *testing.T) {
+	repo := newTestRepo(t)
+
+	t.Setenv("GIT_COMMITTER_DATE", "2025-01-01T10:00:00Z")
+	writeTestFile(t, repo, "content/index.md", "v1")
+	writeTestFile(t, repo, "content/about.md", "about")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "first")
+	first := strings.TrimSpace(runGit(t, repo, "rev-parse", "HEAD"))
+
+	t.Setenv("GIT_COMMITTER_DATE", "2025-02-01T10:00:00Z")
+	writeTestFile(t, repo, "content/index.md", "v2")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "second")
+	second := strings.TrimSpace(runGit(t, repo, "rev-parse", "HEAD"))
+
+	build := &Build{}
+	if err := build.FromDir(os.DirFS(filepath.Join(repo, "content")), "."); err != nil {
+		t.Fatalf("FromDir() returned error: %v", err)
+	}
+	build.Assets = append(build.Assets, &Asset{Path: "/untracked.md", Meta: map[string]any{}})
+	build.Filter(WithPath("/about.md"))[0].Meta["LastModified"] = "2020-01-01"
+
+	if err := build.AddGitHistory(repo, "", "content"); err != nil {
+		t.Fatalf("AddGitHistory() returned error: %v", err)
+	}
+
+	index := build.Filter(WithPath("/index.md"))[0]
+	expectedIndex := map[string]any{
+		"LastModified": "2025-02-01T10:00:00+00:00",
+		"Created":      "2025-01-01T10:00:00+00:00",
+		"Author":       "Test Author",
+		"CommitHash":   second,
+	}
+	for key, want := range expectedIndex {
+		if index.Meta[key] != want {
+			t.Errorf("index Meta[%q] = %v, want %v", key, index.Meta[key], want)
+		}
+	}
+
+	// existing meta is preserved
+	about := build.Filter(WithPath("/about.md"))[0]
+	if about.Meta["LastModified"] != "2020-01-01" {
+		t.Errorf("about Meta[\"LastModified\"] = %v, want 2020-01-01", about.Meta["LastModified"])
+	}
+	if about.Meta["CommitHash"] != first {
+		t.Errorf("about Meta[\"CommitHash\"] = %v, want %v", about.Meta["CommitHash"], first)
+	}
+
+	untracked := build.Filter(WithPath("/untracked.md"))[0]
+	if len(untracked.Meta) != 0 {
+		t.Errorf("untracked Meta = %v, want empty", untracked.Meta)
+	}
+}
+
+func TestAddGitHistory_Error(t *testing.T) {
+	repo := newTestRepo(t)
+
+	build := &Build{Assets: Assets{&Asset{Path: "/index.md"}}}
+	if err := build.AddGitHistory(repo, "does-not-exist", ""); err == nil {
+		t.Error("expected error for unknown ref, got nil")
+	}
+}
+
+func TestFromGit_OnlyReadsRoot(t *testing.T) {
+	repo := newTestRepo(t)
+
+	writeTestFile(t, repo, "site/content/index.md", "index")
+	writeTestFile(t, repo, "site/content/run.sh", "#!/bin/sh")
+	writeTestFile(t, repo, "site/content-old/index.md", "old")
+	writeTestFile(t, repo, "other/big.bin", "not read")
+	if err := os.Chmod(filepath.Join(repo, "site/content/run.sh"), 0755); err != nil {
+		t.Fatalf("failed to chmod: %v", err)
+	}
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "first")
+
+	tree, err := gitTree(repo, "HEAD", "site/content")
+	if err != nil {
+		t.Fatalf("gitTree() returned error: %v", err)
+	}
+	if paths := assetPaths(tree); len(paths) != 2 || paths[0] != "site/content/index.md" || paths[1] != "site/content/run.sh" {
+		t.Errorf("gitTree() read %v, want only the files under root", paths)
+	}
+
+	// root is relative to the top of the repository, even from a subdirectory
+	build := &Build{}
+	if err := build.FromGit(filepath.Join(repo, "site"), "HEAD", "site/content"); err != nil {
+		t.Fatalf("FromGit() returned error: %v", err)
+	}
+	if paths := assetPaths(build.Assets); len(paths) != 2 || paths[0] != "/index.md" {
+		t.Errorf("FromGit() loaded %v", paths)
+	}
+	if mode := build.Filter(WithPath("/run.sh"))[0].Meta["Mode"]; mode != fs.FileMode(0755) {
+		t.Errorf("run.sh Mode = %v, want %v", mode, fs.FileMode(0755))
+	}
+}
+
+func TestAddGitHistory_SubdirectoryAndRenames(t *testing.T) {
+	repo := newTestRepo(t)
+
+	t.Setenv("GIT_COMMITTER_DATE", "2025-03-01T10:00:00Z")
+	writeTestFile(t, repo, "site/content/a.md", "a")
+	writeTestFile(t, repo, "site/content/b.md", "b")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "first")
+
+	site := filepath.Join(repo, "site")
+	expected := "2025-03-01T10:00:00+00:00"
+
+	// loaded from root, and renamed since
+	build := &Build{}
+	if err := build.FromDir(os.DirFS(filepath.Join(site, "content")), "."); err != nil {
+		t.Fatalf("FromDir() returned error: %v", err)
+	}
+	build.Filter(WithPath("/a.md"))[0].Path = "/a.html"
+
+	// loaded from repoPath
+	fromSite := &Build{}
+	if err := fromSite.FromDir(os.DirFS(site), "content"); err != nil {
+		t.Fatalf("FromDir() returned error: %v", err)
+	}
+
+	for _, b := range []*Build{build, fromSite} {
+		if err := b.AddGitHistory(site, "", "content"); err != nil {
+			t.Fatalf("AddGitHistory() returned error: %v", err)
+		}
+		for _, asset := range b.Assets {
+			if asset.Meta["LastModified"] != expected {
+				t.Errorf("%s (from %v) LastModified = %v, want %v", asset.Path, asset.Meta["SourcePath"], asset.Meta["LastModified"], expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package sitetools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AssetHandler is an http.Handler that serves assets from memory by their
+// Path, so a built site can be served without writing it to disk.
+//
+// A request for a directory is served its "index.html", redirecting to add
+// the trailing slash if needed. The Content-Type is the "ContentType" meta of
+// the asset, as set by AddSitemap and AddRobotsTxt, or else goes by its
+// extension or contents. Each response has an ETag from a hash of the
+// contents, so conditional requests get 304 Not Modified.
+//
+// The assets are indexed on the first request, so they must not change after
+// that. Use a new AssetHandler for each build.
+type AssetHandler struct {
+	Assets Assets
+	// NotFound is the Path of the asset to serve, with a 404 status, for paths
+	// that have no asset, e.g. "/404.html" (default: a plain text message)
+	NotFound string
+
+	once  sync.Once
+	index map[string]*servedAsset
+}
+
+// servedAsset is an asset with the headers it is served with.
+type servedAsset struct {
+	*Asset
+	contentType string
+	etag        string
+}
+
+func (h *AssetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(h.buildIndex)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := cleanPath(r.URL.Path)
+	asset, ok := h.index[name]
+	if !ok {
+		if _, ok := h.index[path.Join(name, "index.html")]; ok {
+			if !strings.HasSuffix(r.URL.Path, "/") {
+				target := name + "/"
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, http.StatusMovedPermanently)
+				return
+			}
+			asset = h.index[path.Join(name, "index.html")]
+		}
+	}
+
+	if asset == nil {
+		h.serveNotFound(w, r)
+		return
+	}
+
+	if asset.contentType != "" {
+		w.Header().Set("Content-Type", asset.contentType)
+	}
+	w.Header().Set("ETag", asset.etag)
+	http.ServeContent(w, r, asset.Path, time.Time{}, bytes.NewReader(asset.Data))
+}
+
+func (h *AssetHandler) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	asset, ok := h.index[cleanPath(h.NotFound)]
+	if h.NotFound == "" || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := asset.contentType
+	if contentType == "" {
+		contentType = http.DetectContentType(asset.Data)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		w.Write(asset.Data)
+	}
+}
+
+func (h *AssetHandler) buildIndex() {
+	h.index = make(map[string]*servedAsset, len(h.Assets))
+	for _, asset := range h.Assets {
+		sum := sha256.Sum256(asset.Data)
+		// later assets win, as when writing them
+		h.index[cleanPath(asset.Path)] = &servedAsset{
+			Asset:       asset,
+			contentType: assetContentType(asset),
+			etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+		}
+	}
+}
+
+// assetContentType returns the "ContentType" meta of an asset, or else the type
+// for its extension, if known.
+func assetContentType(asset *Asset) string {
+	if contentType, ok := asset.Meta["ContentType"].(string); ok && contentType != "" {
+		return contentType
+	}
+	return mime.TypeByExtension(path.Ext(asset.Path))
+}
@@ -0,0 +1,110 @@
+package sitetools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAssetHandler(t *testing.T) {
+	handler := &AssetHandler{
+		Assets: Assets{
+			newTestAsset("/index.html", "<p>home</p>", nil),
+			newTestAsset("/blog/index.html", "<p>blog</p>", nil),
+			newTestAsset("/style.css", "body {}", nil),
+			newTestAsset("/sitemap.xml", "<urlset></urlset>", map[string]any{"ContentType": "application/xml"}),
+			newTestAsset("/feed", "<rss></rss>", map[string]any{"ContentType": "application/rss+xml"}),
+			newTestAsset("/LICENSE", "plain text", nil),
+			newTestAsset("/404.html", "<p>not found</p>", nil),
+		},
+		NotFound: "/404.html",
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		status      int
+		contentType string
+		body        string
+		location    string
+	}{
+		{"root index", "GET", "/", 200, "text/html; charset=utf-8", "<p>home</p>", ""},
+		{"file", "GET", "/index.html", 200, "text/html; charset=utf-8", "<p>home</p>", ""},
+		{"directory index", "GET", "/blog/", 200, "text/html; charset=utf-8", "<p>blog</p>", ""},
+		{"directory redirect", "GET", "/blog?page=2", 301, "", "", "/blog/?page=2"},
+		{"extension", "GET", "/style.css", 200, "text/css; charset=utf-8", "body {}", ""},
+		{"content type meta", "GET", "/sitemap.xml", 200, "application/xml", "<urlset></urlset>", ""},
+		{"content type meta without extension", "GET", "/feed", 200, "application/rss+xml", "<rss></rss>", ""},
+		{"sniffed", "GET", "/LICENSE", 200, "text/plain; charset=utf-8", "plain text", ""},
+		{"not found", "GET", "/missing.html", 404, "text/html; charset=utf-8", "<p>not found</p>", ""},
+		{"head", "HEAD", "/index.html", 200, "text/html; charset=utf-8", "", ""},
+		{"method not allowed", "POST", "/index.html", 405, "text/plain; charset=utf-8", "Method Not Allowed\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.contentType)
+			}
+			if tt.status != 301 && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if tt.location != "" && rec.Header().Get("Location") != tt.location {
+				t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), tt.location)
+			}
+		})
+	}
+}
+
+func TestAssetHandler_ETag(t *testing.T) {
+	handler := &AssetHandler{Assets: Assets{
+		newTestAsset("/a.html", "same", nil),
+		newTestAsset("/b.html", "same", nil),
+		newTestAsset("/c.html", "different", nil),
+	}}
+
+	etag := func(path string) string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Header().Get("ETag")
+	}
+
+	a := etag("/a.html")
+	if a == "" || a[0] != '"' {
+		t.Fatalf("ETag = %q, want a quoted hash", a)
+	}
+	if etag("/b.html") != a {
+		t.Errorf("assets with the same content have different ETags")
+	}
+	if etag("/c.html") == a {
+		t.Errorf("assets with different content have the same ETag")
+	}
+
+	req := httptest.NewRequest("GET", "/a.html", nil)
+	req.Header.Set("If-None-Match", a)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional request status = %d, want 304", rec.Code)
+	}
+}
+
+func TestAssetHandler_DefaultNotFound(t *testing.T) {
+	handler := &AssetHandler{Assets: Assets{newTestAsset("/index.html", "home", nil)}, NotFound: "/missing-404.html"}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if rec.Body.String() != "404 page not found\n" {
+		t.Errorf("body = %q, want the default message", rec.Body.String())
+	}
+}
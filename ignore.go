@@ -0,0 +1,148 @@
+package sitetools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// IgnoreFile is the name of the file, at the root of a loaded directory, whose
+// patterns are added to Build.Ignore. It is never loaded as an asset itself.
+const IgnoreFile = ".siteignore"
+
+// DefaultIgnore lists patterns for files that rarely belong in a site, such as
+// version control directories and editor or OS clutter.
+var DefaultIgnore = []string{
+	".git/",
+	".hg/",
+	".svn/",
+	".DS_Store",
+	"Thumbs.db",
+	"*.swp",
+	"*.swo",
+	"*~",
+	"#*#",
+}
+
+type ignorePattern struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules is an ordered list of gitignore-style patterns. As with
+// .gitignore, the last pattern to match a path decides whether it is ignored.
+type ignoreRules []ignorePattern
+
+// parseIgnore parses gitignore-style patterns. Blank lines and lines starting
+// with "#" are skipped, "!" negates a pattern, a trailing "/" only matches
+// directories, a pattern containing any other "/" is relative to the root
+// rather than matching at any depth, and "**" matches any number of
+// directories.
+func parseIgnore(lines []string) (ignoreRules, error) {
+	var rules ignoreRules
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var pattern ignorePattern
+		if strings.HasPrefix(line, "!") {
+			pattern.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			pattern.anchored = true
+			line = strings.TrimLeft(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		pattern.segments = strings.Split(line, "/")
+		for _, segment := range pattern.segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+			}
+		}
+
+		rules = append(rules, pattern)
+	}
+	return rules, nil
+}
+
+// readIgnoreFile returns the lines of the IgnoreFile in root, if there is one.
+func readIgnoreFile(fsys fs.FS, root string) ([]string, error) {
+	data, err := fs.ReadFile(fsys, path.Join(root, IgnoreFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))), "\n"), nil
+}
+
+// ignored reports whether name, a slash separated path relative to the root,
+// is excluded by the rules.
+func (rules ignoreRules) ignored(name string, isDir bool) bool {
+	segments := strings.Split(name, "/")
+
+	ignored := false
+	for _, pattern := range rules {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+
+		var matched bool
+		if pattern.anchored {
+			matched = matchSegments(pattern.segments, segments)
+		} else {
+			matched = matchSegments(pattern.segments, segments[len(segments)-1:])
+		}
+
+		if matched {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == "**" {
+		// a trailing "**" matches everything inside, but not the directory itself
+		if len(pattern) == 1 {
+			return len(name) > 0
+		}
+		for i := range len(name) + 1 {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
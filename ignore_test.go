@@ -0,0 +1,133 @@
+package sitetools
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"basename at root", []string{".DS_Store"}, ".DS_Store", false, true},
+		{"basename nested", []string{".DS_Store"}, "a/b/.DS_Store", false, true},
+		{"glob", []string{"*.swp"}, "posts/.post.md.swp", false, true},
+		{"glob no match", []string{"*.swp"}, "posts/post.md", false, false},
+		{"dir only matches dir", []string{"drafts/"}, "blog/drafts", true, true},
+		{"dir only skips file", []string{"drafts/"}, "blog/drafts", false, false},
+		{"anchored", []string{"/drafts"}, "drafts", true, true},
+		{"anchored not nested", []string{"/drafts"}, "blog/drafts", true, false},
+		{"anchored with slash", []string{"blog/drafts"}, "blog/drafts", true, true},
+		{"anchored with slash not nested", []string{"blog/drafts"}, "x/blog/drafts", true, false},
+		{"leading double star", []string{"**/tmp"}, "a/b/tmp", true, true},
+		{"leading double star at root", []string{"**/tmp"}, "tmp", true, true},
+		{"middle double star", []string{"a/**/b.txt"}, "a/x/y/b.txt", false, true},
+		{"middle double star zero dirs", []string{"a/**/b.txt"}, "a/b.txt", false, true},
+		{"trailing double star", []string{"a/**"}, "a/b/c", false, true},
+		{"trailing double star not dir itself", []string{"a/**"}, "a", true, false},
+		{"negation", []string{"*.md", "!keep.md"}, "keep.md", false, false},
+		{"negation other", []string{"*.md", "!keep.md"}, "drop.md", false, true},
+		{"last match wins", []string{"!keep.md", "*.md"}, "keep.md", false, true},
+		{"comments and blanks", []string{"# *.md", "", "   "}, "a.md", false, false},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"trailing spaces", []string{"*.log   "}, "debug.log", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := parseIgnore(tt.patterns)
+			if err != nil {
+				t.Fatalf("parseIgnore() returned error: %v", err)
+			}
+			if got := rules.ignored(tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseIgnore_InvalidPattern(t *testing.T) {
+	if _, err := parseIgnore([]string{"[a-"}); err == nil {
+		t.Error("expected error for invalid pattern, got nil")
+	}
+}
+
+// failingDirFS fails to open anything under the given directory, so walking
+// into it is an error.
+type failingDirFS struct {
+	files fstest.MapFS
+	dir   string
+}
+
+func (f failingDirFS) Open(name string) (fs.File, error) {
+	if name == f.dir || strings.HasPrefix(name, f.dir+"/") {
+		return nil, errors.New("should not be opened: " + name)
+	}
+	return f.files.Open(name)
+}
+
+func TestFromDir_Ignore(t *testing.T) {
+	fsys := failingDirFS{
+		dir: "site/drafts",
+		files: fstest.MapFS{
+			"site/.siteignore":       {Data: []byte("# drafts are not published\ndrafts/\n*.bak\n!keep.bak\n")},
+			"site/index.html":        {Data: []byte("index")},
+			"site/.DS_Store":         {Data: []byte("junk")},
+			"site/.git/HEAD":         {Data: []byte("ref")},
+			"site/drafts/post.md":    {Data: []byte("draft")},
+			"site/posts/post.md":     {Data: []byte("post")},
+			"site/posts/post.md.bak": {Data: []byte("backup")},
+			"site/posts/keep.bak":    {Data: []byte("keep")},
+			"site/posts/.siteignore": {Data: []byte("nested")},
+		},
+	}
+
+	build := &Build{Ignore: DefaultIgnore}
+	if err := build.FromDir(fsys, "site"); err != nil {
+		t.Fatalf("FromDir() returned error: %v", err)
+	}
+
+	expected := []string{"/index.html", "/posts/.siteignore", "/posts/keep.bak", "/posts/post.md"}
+	var got []string
+	for _, asset := range build.Assets {
+		got = append(got, asset.Path)
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("loaded assets = %v, want %v", got, expected)
+	}
+}
+
+func TestFromDir_IgnoreDotfilesAtRoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		".siteignore": {Data: []byte("secret.txt")},
+		".well-known": {Data: []byte("kept")},
+		"secret.txt":  {Data: []byte("secret")},
+	}
+
+	build := &Build{}
+	if err := build.FromDir(fsys, "."); err != nil {
+		t.Fatalf("FromDir() returned error: %v", err)
+	}
+
+	if len(build.Assets) != 1 {
+		t.Fatalf("expected 1 asset, got %d", len(build.Assets))
+	}
+	if build.Assets[0].Path != "/.well-known" {
+		t.Errorf("expected /.well-known, got %s", build.Assets[0].Path)
+	}
+}
+
+func TestFromDir_InvalidIgnore(t *testing.T) {
+	build := &Build{Ignore: []string{"[a-"}}
+	if err := build.FromDir(fstest.MapFS{"a.txt": {}}, "."); err == nil {
+		t.Error("expected error for invalid ignore pattern, got nil")
+	}
+}
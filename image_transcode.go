@@ -2,6 +2,7 @@ package sitetools
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/color/palette"
@@ -32,6 +33,14 @@ type ImageTranscoder struct {
 }
 
 func (it ImageTranscoder) Transform(asset *Asset) error {
+	return it.TransformContext(context.Background(), asset)
+}
+
+// TransformContext transcodes the asset, stopping with the context's error if
+// ctx is cancelled. The context is checked before decoding, before encoding
+// and between the frames of an animation, but a single decode or encode call
+// into an image library runs to completion.
+func (it ImageTranscoder) TransformContext(ctx context.Context, asset *Asset) error {
 	if it.ToFormat == "" {
 		return fmt.Errorf("ToFormat is required")
 	}
@@ -41,8 +50,10 @@ func (it ImageTranscoder) Transform(asset *Asset) error {
 		return fmt.Errorf("failed to identify input image format: %w", err)
 	}
 
-	reader := bytes.NewReader(asset.Data)
-	frames, err := inFormat.decode(reader)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	frames, err := inFormat.decode(bytes.NewReader(asset.Data))
 	if err != nil {
 		return fmt.Errorf("failed to decode input image: %w", err)
 	}
@@ -63,8 +74,14 @@ func (it ImageTranscoder) Transform(asset *Asset) error {
 		frames = frames.firstFrame()
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	var data bytes.Buffer
-	if err := outFormat.encode(&data, frames); err != nil {
+	if err := outFormat.encode(ctx, &data, frames); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("failed to encode image to %s: %w", it.ToFormat, err)
 	}
 
@@ -169,7 +186,7 @@ func standardDecoder(reader *bytes.Reader) (imageFrames, error) {
 // ---------- Encoder ----------
 
 type imageEncoder interface {
-	encode(w *bytes.Buffer, frames imageFrames) error
+	encode(ctx context.Context, w *bytes.Buffer, frames imageFrames) error
 	extension() string
 	supportsAnimation() bool
 }
@@ -226,7 +243,7 @@ func (pngFormat) decode(r *bytes.Reader) (imageFrames, error) {
 	return standardDecoder(r)
 }
 
-func (pngFormat) encode(w *bytes.Buffer, frames imageFrames) error {
+func (pngFormat) encode(_ context.Context, w *bytes.Buffer, frames imageFrames) error {
 	return png.Encode(w, frames.frames[0])
 }
 
@@ -252,7 +269,7 @@ func (jpegFormat) decode(r *bytes.Reader) (imageFrames, error) {
 	return standardDecoder(r)
 }
 
-func (e jpegFormat) encode(w *bytes.Buffer, frames imageFrames) error {
+func (e jpegFormat) encode(_ context.Context, w *bytes.Buffer, frames imageFrames) error {
 	return jpeg.Encode(w, frames.frames[0], &jpeg.Options{Quality: e.quality})
 }
 
@@ -298,9 +315,13 @@ func (gifFormat) decode(r *bytes.Reader) (imageFrames, error) {
 	}, nil
 }
 
-func (gifFormat) encode(w *bytes.Buffer, frames imageFrames) error {
+func (gifFormat) encode(ctx context.Context, w *bytes.Buffer, frames imageFrames) error {
+	// dithering is the slow part, so check for cancellation between frames
 	palettedFrames := make([]*image.Paletted, len(frames.frames))
 	for i, frame := range frames.frames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		palettedFrames[i] = toPaletted(frame)
 	}
 
@@ -374,7 +395,7 @@ func (webpFormat) decode(r *bytes.Reader) (imageFrames, error) {
 	}, nil
 }
 
-func (e webpFormat) encode(w *bytes.Buffer, frames imageFrames) error {
+func (e webpFormat) encode(_ context.Context, w *bytes.Buffer, frames imageFrames) error {
 	if frames.isAnimated() {
 		durations := make([]uint, len(frames.frames))
 		disposals := make([]uint, len(frames.frames))
@@ -464,7 +485,7 @@ func (avifFormat) decode(r *bytes.Reader) (imageFrames, error) {
 	}, nil
 }
 
-func (e avifFormat) encode(w *bytes.Buffer, frames imageFrames) error {
+func (e avifFormat) encode(_ context.Context, w *bytes.Buffer, frames imageFrames) error {
 	quality := e.quality
 	if quality <= 0 {
 		quality = genavif.DefaultQuality
@@ -2,7 +2,9 @@ package sitetools
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"image"
 	"image/gif"
@@ -410,6 +412,36 @@ func TestTranscode_UnsupportedFormat(t *testing.T) {
 	}
 }
 
+func TestTranscode_CancelledContext(t *testing.T) {
+	asset := &Asset{Data: pngRGB, Path: "test.png"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := (ImageTranscoder{ToFormat: ImageFormatAVIF}).TransformContext(ctx, asset)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("TransformContext() = %v, want %v", err, context.Canceled)
+	}
+	if asset.Path != "test.png" || !bytes.Equal(asset.Data, pngRGB) {
+		t.Error("asset was modified despite cancellation")
+	}
+}
+
+func TestTranscode_CancelledBetweenFrames(t *testing.T) {
+	frames, err := gifFormat{}.decode(bytes.NewReader(gifAnimRGB))
+	if err != nil {
+		t.Fatalf("decode() returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var data bytes.Buffer
+	if err := (gifFormat{}).encode(ctx, &data, frames); !errors.Is(err, context.Canceled) {
+		t.Errorf("encode() = %v, want %v", err, context.Canceled)
+	}
+}
+
 func TestTranscode_QualityClamping(t *testing.T) {
 	tests := []struct {
 		name    string
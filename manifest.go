@@ -0,0 +1,87 @@
+package sitetools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// manifestEntry describes a single output file in a manifest.
+type manifestEntry struct {
+	Path        string         `json:"path"`
+	Size        int            `json:"size"`
+	SHA256      string         `json:"sha256"`
+	ContentType string         `json:"contentType"`
+	Source      string         `json:"source,omitempty"`
+	Meta        map[string]any `json:"meta,omitempty"`
+}
+
+// AddManifest adds a JSON asset at filePath listing every file the build
+// writes, in order of their path, for deployment tools to use, e.g. to work out
+// which files changed since the last deploy. Each entry has the file's "path",
+// "size", "sha256" and "contentType", the "source" it was loaded from when it
+// has "SourcePath" meta, which maps sources to their output even after they
+// were renamed, and the values of any of metaKeys the asset has, under "meta".
+//
+// The manifest does not list itself and is excluded from the sitemap, so add
+// it after everything else.
+func (build *Build) AddManifest(filePath string, metaKeys ...string) error {
+	names, byName, err := build.Assets.outputFiles()
+	if err != nil {
+		return err
+	}
+
+	manifest := struct {
+		Assets []manifestEntry `json:"assets"`
+	}{Assets: make([]manifestEntry, 0, len(names))}
+
+	for _, name := range names {
+		asset := byName[name]
+
+		contentType := assetContentType(asset)
+		if contentType == "" {
+			contentType = http.DetectContentType(asset.Data)
+		}
+
+		sum := sha256.Sum256(asset.Data)
+		entry := manifestEntry{
+			Path:        "/" + name,
+			Size:        len(asset.Data),
+			SHA256:      hex.EncodeToString(sum[:]),
+			ContentType: contentType,
+		}
+		if sourcePath, ok := asset.Meta["SourcePath"].(string); ok {
+			entry.Source = sourcePath
+		}
+
+		for _, key := range metaKeys {
+			value, ok := asset.Meta[key]
+			if !ok {
+				continue
+			}
+			if entry.Meta == nil {
+				entry.Meta = map[string]any{}
+			}
+			// YAML front matter may hold maps JSON can't encode
+			entry.Meta[key] = normalizeYAML(value)
+		}
+
+		manifest.Assets = append(manifest.Assets, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	build.Assets = append(build.Assets, &Asset{
+		Path: cleanPath(filePath),
+		Data: append(data, '\n'),
+		Meta: map[string]any{
+			"ContentType":    "application/json",
+			"SitemapExclude": true,
+		},
+	})
+	return nil
+}
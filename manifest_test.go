@@ -0,0 +1,78 @@
+package sitetools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuild_AddManifest(t *testing.T) {
+	build := &Build{Assets: Assets{
+		newTestAsset("/posts/first/index.html", "<p>first</p>", map[string]any{
+			"SourcePath": "posts/first.md",
+			"Title":      "First",
+			"Tags":       []any{"go"},
+			"Author":     map[any]any{"name": "Ann"},
+		}),
+		newTestAsset("/img/logo.webp", "RIFF", map[string]any{"SourcePath": "img/logo.png"}),
+		newTestAsset("/LICENSE", "plain text", nil),
+		newTestAsset("/feed", "<rss/>", map[string]any{"ContentType": "application/rss+xml"}),
+	}}
+
+	if err := build.AddManifest("manifest.json", "Title", "Tags", "Author", "Missing"); err != nil {
+		t.Fatalf("AddManifest() returned error: %v", err)
+	}
+	manifestAsset := build.Assets[len(build.Assets)-1]
+	if manifestAsset.Path != "/manifest.json" {
+		t.Fatalf("expected manifest at /manifest.json, got %s", manifestAsset.Path)
+	}
+	if manifestAsset.Meta["SitemapExclude"] != true || manifestAsset.Meta["ContentType"] != "application/json" {
+		t.Errorf("manifest Meta = %v", manifestAsset.Meta)
+	}
+
+	var manifest struct {
+		Assets []map[string]any `json:"assets"`
+	}
+	if err := json.Unmarshal(manifestAsset.Data, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v\n%s", err, manifestAsset.Data)
+	}
+
+	hash := func(data string) string {
+		sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+	expected := []map[string]any{
+		{"path": "/LICENSE", "size": 10.0, "sha256": hash("plain text"), "contentType": "text/plain; charset=utf-8"},
+		{"path": "/feed", "size": 6.0, "sha256": hash("<rss/>"), "contentType": "application/rss+xml"},
+		{"path": "/img/logo.webp", "size": 4.0, "sha256": hash("RIFF"), "contentType": "image/webp", "source": "img/logo.png"},
+		{
+			"path": "/posts/first/index.html", "size": 12.0, "sha256": hash("<p>first</p>"),
+			"contentType": "text/html; charset=utf-8", "source": "posts/first.md",
+			"meta": map[string]any{"Title": "First", "Tags": []any{"go"}, "Author": map[string]any{"name": "Ann"}},
+		},
+	}
+	if !reflect.DeepEqual(manifest.Assets, expected) {
+		t.Errorf("manifest assets =\n%v\nwant\n%v", manifest.Assets, expected)
+	}
+
+	// the manifest is not in the sitemap
+	if err := build.AddSitemap("https://site"); err != nil {
+		t.Fatalf("AddSitemap() returned error: %v", err)
+	}
+	if sitemap := build.Assets.Filter(WithPath("/sitemap.xml"))[0]; strings.Contains(string(sitemap.Data), "manifest.json") {
+		t.Errorf("sitemap lists the manifest:\n%s", sitemap.Data)
+	}
+}
+
+func TestBuild_AddManifest_Empty(t *testing.T) {
+	build := &Build{}
+	if err := build.AddManifest("/manifest.json"); err != nil {
+		t.Fatalf("AddManifest() returned error: %v", err)
+	}
+	if data := string(build.Assets[0].Data); data != "{\n  \"assets\": []\n}\n" {
+		t.Errorf("empty manifest = %q", data)
+	}
+}
@@ -0,0 +1,105 @@
+package sitetools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Pipeline runs named stages of transformers over a set of assets, one stage
+// after another, and records statistics for each stage.
+type Pipeline struct {
+	Stages []Stage
+
+	stats []StageStats
+}
+
+// Stage is a named step of a Pipeline.
+type Stage struct {
+	Name string
+	// Filters limit the stage to the assets matching all of them (default: all assets)
+	Filters      []Filter
+	Transformers []Transformer
+}
+
+// StageStats describes a single run of a Stage.
+type StageStats struct {
+	Name     string
+	Duration time.Duration
+	// Assets is the number of assets the stage ran on.
+	Assets int
+	// BytesIn and BytesOut are the total size of those assets before and after the stage.
+	BytesIn  int
+	BytesOut int
+}
+
+// Run is RunContext with a background context.
+func (p *Pipeline) Run(assets Assets) error {
+	return p.RunContext(context.Background(), assets)
+}
+
+// RunContext runs each stage over the assets matching its filters, like
+// Assets.TransformContext, stopping at the first error. Statistics from any
+// previous run are discarded.
+func (p *Pipeline) RunContext(ctx context.Context, assets Assets) error {
+	p.stats = make([]StageStats, 0, len(p.Stages))
+
+	for _, stage := range p.Stages {
+		selected := assets.Filter(stage.Filters...)
+
+		stats := StageStats{
+			Name:    stage.Name,
+			Assets:  len(selected),
+			BytesIn: selected.size(),
+		}
+
+		start := time.Now()
+		err := selected.TransformContext(ctx, stage.Transformers...)
+		stats.Duration = time.Since(start)
+		stats.BytesOut = selected.size()
+
+		p.stats = append(p.stats, stats)
+
+		if err != nil {
+			var buildErr *BuildError
+			if errors.As(err, &buildErr) {
+				buildErr.Stage = stage.Name
+			}
+			return fmt.Errorf("stage %s: %w", stage.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Stats returns the statistics of each stage from the last run, in order. A
+// stage that failed is included, but stages after it are not.
+func (p *Pipeline) Stats() []StageStats {
+	return p.stats
+}
+
+// Report writes the statistics from the last run as a table.
+func (p *Pipeline) Report(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "stage\ttime\tassets\tbytes in\tbytes out\t")
+
+	var total time.Duration
+	for _, stats := range p.stats {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t\n", stats.Name, stats.Duration.Round(time.Microsecond), stats.Assets, stats.BytesIn, stats.BytesOut)
+		total += stats.Duration
+	}
+	fmt.Fprintf(tw, "total\t%s\t\t\t\t\n", total.Round(time.Microsecond))
+
+	return tw.Flush()
+}
+
+func (assets Assets) size() int {
+	total := 0
+	for _, asset := range assets {
+		total += len(asset.Data)
+	}
+	return total
+}
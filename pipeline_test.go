@@ -0,0 +1,111 @@
+package sitetools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPipeline_Run(t *testing.T) {
+	assets := Assets{
+		newTestAsset("/index.md", "# Hello", nil),
+		newTestAsset("/about.md", "# About", nil),
+		newTestAsset("/styles.css", "body {  color : red ; }", nil),
+	}
+
+	pipeline := &Pipeline{
+		Stages: []Stage{
+			{Name: "markdown", Filters: []Filter{WithExtensions(".md")}, Transformers: []Transformer{MarkdownTransformer{}}},
+			{Name: "minify", Transformers: []Transformer{MinifyTransformer{}}},
+			{Name: "nothing", Filters: []Filter{WithExtensions(".png")}, Transformers: []Transformer{MinifyTransformer{}}},
+		},
+	}
+
+	if err := pipeline.Run(assets); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	if assets[0].Path != "/index.html" || string(assets[0].Data) != "<h1>Hello</h1>" {
+		t.Errorf("unexpected first asset: %s %q", assets[0].Path, assets[0].Data)
+	}
+
+	stats := pipeline.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 stage stats, got %d", len(stats))
+	}
+
+	expected := []StageStats{
+		{Name: "markdown", Assets: 2, BytesIn: 14, BytesOut: 30},
+		{Name: "minify", Assets: 3, BytesIn: 53, BytesOut: 43},
+		{Name: "nothing", Assets: 0, BytesIn: 0, BytesOut: 0},
+	}
+	for i, want := range expected {
+		got := stats[i]
+		got.Duration = 0
+		if got != want {
+			t.Errorf("stage %d stats = %+v, want %+v", i, got, want)
+		}
+	}
+	if stats[0].Duration <= 0 {
+		t.Errorf("markdown stage Duration = %v, want > 0", stats[0].Duration)
+	}
+
+	var report bytes.Buffer
+	if err := pipeline.Report(&report); err != nil {
+		t.Fatalf("Report() returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 report lines, got %d:\n%s", len(lines), report.String())
+	}
+	for i, name := range []string{"stage", "markdown", "minify", "nothing", "total"} {
+		if !strings.HasPrefix(strings.TrimSpace(lines[i]), name) {
+			t.Errorf("report line %d = %q, want it to start with %q", i, lines[i], name)
+		}
+	}
+}
+
+func TestPipeline_RunError(t *testing.T) {
+	assets := Assets{newTestAsset("/index.html", "", nil)}
+
+	after := &MockTransformer{}
+	pipeline := &Pipeline{
+		Stages: []Stage{
+			{Name: "ok", Transformers: []Transformer{&MockTransformer{}}},
+			{Name: "failing", Transformers: []Transformer{&MockTransformer{
+				TransformFunc: func(*Asset) error { return os.ErrPermission },
+			}}},
+			{Name: "after", Transformers: []Transformer{after}},
+		},
+	}
+
+	err := pipeline.Run(assets)
+	if !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("Run() returned %v, want %v", err, os.ErrPermission)
+	}
+	if !strings.Contains(err.Error(), "stage failing") {
+		t.Errorf("Run() error %q does not name the stage", err)
+	}
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) || buildErr.Path != "/index.html" {
+		t.Errorf("Run() error does not wrap a BuildError for the asset")
+	} else if buildErr.Stage != "failing" {
+		t.Errorf("BuildError.Stage = %q, want %q", buildErr.Stage, "failing")
+	}
+
+	if after.CalledCount != 0 {
+		t.Errorf("stage after failure ran %d times", after.CalledCount)
+	}
+	if len(pipeline.Stats()) != 2 {
+		t.Errorf("expected stats for 2 stages, got %d", len(pipeline.Stats()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pipeline.RunContext(ctx, assets); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunContext() with cancelled context = %v, want %v", err, context.Canceled)
+	}
+}
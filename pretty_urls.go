@@ -0,0 +1,46 @@
+package sitetools
+
+import (
+	"path"
+	"strings"
+)
+
+// PrettyURLTransformer moves HTML pages to directory indexes, so
+// /blog/post.html becomes /blog/post/index.html and can be served as
+// /blog/post/. The URL a page is served at is recorded in its "URL" meta, which
+// AddSitemap uses instead of the Path, so /blog/post.html is listed as
+// /blog/post/ and /index.html as /.
+type PrettyURLTransformer struct {
+	// Exceptions are path.Match patterns for the Paths of pages to leave where
+	// they are, e.g. "/404.html"
+	Exceptions []string
+}
+
+func (t PrettyURLTransformer) Transform(asset *Asset) error {
+	if path.Ext(asset.Path) != ".html" {
+		return nil
+	}
+	if asset.Meta == nil {
+		asset.Meta = map[string]any{}
+	}
+
+	for _, pattern := range t.Exceptions {
+		matched, err := path.Match(pattern, asset.Path)
+		if err != nil {
+			return err
+		}
+		if matched {
+			asset.Meta["URL"] = asset.Path
+			return nil
+		}
+	}
+
+	dir := strings.TrimSuffix(asset.Path, ".html")
+	if path.Base(asset.Path) == "index.html" {
+		dir = path.Dir(asset.Path)
+	}
+
+	asset.Path = path.Join(dir, "index.html")
+	asset.Meta["URL"] = strings.TrimSuffix(dir, "/") + "/"
+	return nil
+}
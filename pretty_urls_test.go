@@ -0,0 +1,71 @@
+package sitetools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrettyURLTransformer(t *testing.T) {
+	transformer := PrettyURLTransformer{Exceptions: []string{"/404.html", "/errors/*.html"}}
+
+	tests := []struct {
+		path         string
+		expectedPath string
+		expectedURL  any
+	}{
+		{"/blog/post.html", "/blog/post/index.html", "/blog/post/"},
+		{"/about.html", "/about/index.html", "/about/"},
+		{"/index.html", "/index.html", "/"},
+		{"/blog/index.html", "/blog/index.html", "/blog/"},
+		{"/404.html", "/404.html", "/404.html"},
+		{"/errors/500.html", "/errors/500.html", "/errors/500.html"},
+		{"/style.css", "/style.css", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			asset := newTestAsset(tt.path, "", nil)
+			if err := transformer.Transform(asset); err != nil {
+				t.Fatalf("Transform() returned error: %v", err)
+			}
+			if asset.Path != tt.expectedPath {
+				t.Errorf("Path = %s, want %s", asset.Path, tt.expectedPath)
+			}
+			if asset.Meta["URL"] != tt.expectedURL {
+				t.Errorf("Meta[\"URL\"] = %v, want %v", asset.Meta["URL"], tt.expectedURL)
+			}
+		})
+	}
+
+	if err := (PrettyURLTransformer{Exceptions: []string{"["}}).Transform(newTestAsset("/a.html", "", nil)); err == nil {
+		t.Error("expected error for invalid exception pattern, got nil")
+	}
+}
+
+func TestAddSitemap_PrettyURLs(t *testing.T) {
+	build := &Build{
+		Assets: Assets{
+			newTestAsset("/index.html", "", nil),
+			newTestAsset("/blog/post.html", "", nil),
+			newTestAsset("/404.html", "", map[string]any{"SitemapExclude": true}),
+			newTestAsset("/style.css", "", nil),
+		},
+	}
+
+	if err := build.Assets.Transform(PrettyURLTransformer{Exceptions: []string{"/404.html"}}); err != nil {
+		t.Fatalf("Transform() returned error: %v", err)
+	}
+	if err := build.AddSitemap("https://site"); err != nil {
+		t.Fatalf("AddSitemap() returned error: %v", err)
+	}
+
+	sitemap := string(build.Assets.Filter(WithPath("/sitemap.xml"))[0].Data)
+	for _, loc := range []string{"https://site/", "https://site/blog/post/", "https://site/style.css"} {
+		if !strings.Contains(sitemap, "<loc>"+loc+"</loc>") {
+			t.Errorf("sitemap does not list %s:\n%s", loc, sitemap)
+		}
+	}
+	if strings.Contains(sitemap, ".html") {
+		t.Errorf("sitemap lists .html paths:\n%s", sitemap)
+	}
+}
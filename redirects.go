@@ -0,0 +1,216 @@
+package sitetools
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// redirect sends requests for From to the page at To.
+type redirect struct {
+	From string
+	To   string
+}
+
+// aliasRedirects returns a redirect for each of the "Aliases" of the assets in
+// the build, sorted by From. An asset's "Aliases" meta is a list of old paths
+// for it, e.g. from front matter, and it is redirected to at its "URL" meta,
+// or else its Path.
+func (build *Build) aliasRedirects() ([]redirect, error) {
+	var redirects []redirect
+	seen := map[string]string{}
+
+	for _, asset := range build.Assets {
+		var aliases []string
+		switch v := asset.Meta["Aliases"].(type) {
+		case nil:
+			continue
+		case string:
+			aliases = []string{v}
+		case []string:
+			aliases = v
+		case []any:
+			for _, alias := range v {
+				s, ok := alias.(string)
+				if !ok {
+					return nil, fmt.Errorf("issue in asset %s: alias %v is not a string", asset.Path, alias)
+				}
+				aliases = append(aliases, s)
+			}
+		default:
+			return nil, fmt.Errorf("issue in asset %s: Aliases must be a list of paths", asset.Path)
+		}
+
+		to := asset.Path
+		if url, ok := asset.Meta["URL"].(string); ok && url != "" {
+			to = url
+		}
+
+		for _, alias := range aliases {
+			from := cleanPath(alias)
+			if other, ok := seen[from]; ok {
+				return nil, fmt.Errorf("issue in asset %s: alias %s is already used by %s", asset.Path, from, other)
+			}
+			seen[from] = asset.Path
+			redirects = append(redirects, redirect{From: from, To: to})
+		}
+	}
+
+	slices.SortFunc(redirects, func(a, b redirect) int {
+		return strings.Compare(a.From, b.From)
+	})
+	return redirects, nil
+}
+
+// AddAliases adds a page for each of the "Aliases" of the assets in the build,
+// such as "Aliases: [/old/path]" in front matter, that redirects to the asset
+// with a meta refresh and names it as the canonical page. An alias without an
+// extension gets an index page, so "/old/path" is written to
+// "/old/path/index.html". The pages are excluded from the sitemap.
+//
+// Run it after PrettyURLTransformer, if using it, so the redirects go to the
+// "URL" of each asset.
+func (build *Build) AddAliases() error {
+	redirects, err := build.aliasRedirects()
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, asset := range build.Assets {
+		existing[cleanPath(asset.Path)] = true
+	}
+
+	for _, r := range redirects {
+		stubPath := r.From
+		if path.Ext(stubPath) == "" {
+			stubPath = path.Join(stubPath, "index.html")
+		}
+		if existing[stubPath] {
+			return fmt.Errorf("alias %s would replace asset %s", r.From, stubPath)
+		}
+
+		to := html.EscapeString(r.To)
+		data := `<!DOCTYPE html><html><head><meta charset="utf-8"><title>Redirecting…</title>` +
+			`<link rel="canonical" href="` + to + `">` +
+			`<meta name="robots" content="noindex">` +
+			`<meta http-equiv="refresh" content="0; url=` + to + `">` +
+			`</head><body><a href="` + to + `">Redirecting…</a></body></html>`
+
+		// two aliases can give the same stub, e.g. "/old" and "/old/index.html"
+		existing[stubPath] = true
+		build.Assets = append(build.Assets, &Asset{
+			Path: stubPath,
+			Data: []byte(data),
+			Meta: map[string]any{
+				"SitemapExclude": true,
+				"RedirectTo":     r.To,
+			},
+		})
+	}
+
+	return nil
+}
+
+// RedirectFormat is the kind of configuration AddRedirects writes.
+type RedirectFormat string
+
+const (
+	// RedirectsNetlify is a _redirects file, as used by Netlify and Cloudflare Pages.
+	RedirectsNetlify RedirectFormat = "netlify"
+	// RedirectsJSON is a JSON object with a "redirects" list, as used by
+	// Vercel's vercel.json.
+	RedirectsJSON RedirectFormat = "json"
+	// RedirectsNginx is a list of nginx rewrite directives to include in a
+	// server block.
+	RedirectsNginx RedirectFormat = "nginx"
+)
+
+// AddRedirects adds an asset at filePath with a permanent redirect for each of
+// the "Aliases" of the assets in the build, like AddAliases but for servers
+// that can redirect themselves, e.g. "/_redirects" with RedirectsNetlify.
+// Paths the format can't hold, such as ones with spaces in a _redirects file,
+// are an error.
+func (build *Build) AddRedirects(filePath string, format RedirectFormat) error {
+	redirects, err := build.aliasRedirects()
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	var contentType string
+	switch format {
+	case RedirectsNetlify:
+		var b strings.Builder
+		for _, r := range redirects {
+			// fields are separated by whitespace, with no way to quote it
+			if err := checkRedirect(r, format, " \t\r\n"); err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "%s %s 301\n", r.From, r.To)
+		}
+		data, contentType = []byte(b.String()), "text/plain"
+
+	case RedirectsJSON:
+		type jsonRedirect struct {
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+			Permanent   bool   `json:"permanent"`
+		}
+		config := struct {
+			Redirects []jsonRedirect `json:"redirects"`
+		}{Redirects: []jsonRedirect{}}
+		for _, r := range redirects {
+			config.Redirects = append(config.Redirects, jsonRedirect{Source: r.From, Destination: r.To, Permanent: true})
+		}
+		data, err = json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return err
+		}
+		data, contentType = append(data, '\n'), "application/json"
+
+	case RedirectsNginx:
+		var b strings.Builder
+		for _, r := range redirects {
+			// these would end or quote the argument, or start a block or variable
+			if err := checkRedirect(r, format, " \t\r\n;{}\"'$\\"); err != nil {
+				return err
+			}
+			pattern := regexp.QuoteMeta(r.From)
+			if path.Ext(r.From) == "" && r.From != "/" {
+				// as served from its index page, with or without the slash
+				pattern += "/?"
+			}
+			fmt.Fprintf(&b, "rewrite ^%s$ %s permanent;\n", pattern, r.To)
+		}
+		data, contentType = []byte(b.String()), "text/plain"
+
+	default:
+		return fmt.Errorf("unknown redirect format %q", format)
+	}
+
+	build.Assets = append(build.Assets, &Asset{
+		Path: cleanPath(filePath),
+		Data: data,
+		Meta: map[string]any{
+			"ContentType":    contentType,
+			"SitemapExclude": true,
+		},
+	})
+	return nil
+}
+
+// checkRedirect returns an error if the paths of r contain any of chars, which
+// format has no way to write.
+func checkRedirect(r redirect, format RedirectFormat, chars string) error {
+	for _, p := range []string{r.From, r.To} {
+		if i := strings.IndexAny(p, chars); i >= 0 {
+			return fmt.Errorf("redirect from %s to %s can't be written in %s format: %q is not allowed in paths", r.From, r.To, format, p[i])
+		}
+	}
+	return nil
+}
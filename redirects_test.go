@@ -0,0 +1,190 @@
+package sitetools
+
+import (
+	"strings"
+	"testing"
+)
+
+func newRedirectTestBuild() *Build {
+	return &Build{Assets: Assets{
+		newTestAsset("/blog/new-post/index.html", "post", map[string]any{
+			"URL":     "/blog/new-post/",
+			"Aliases": []any{"/blog/old-post", "/2019/post.html"},
+		}),
+		newTestAsset("/about.html", "about", map[string]any{"Aliases": "old-about/"}),
+		newTestAsset("/index.html", "home", map[string]any{}),
+	}}
+}
+
+func TestBuild_AddAliases(t *testing.T) {
+	build := newRedirectTestBuild()
+	if err := build.AddAliases(); err != nil {
+		t.Fatalf("AddAliases() returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"/2019/post.html":           "/blog/new-post/",
+		"/blog/old-post/index.html": "/blog/new-post/",
+		"/old-about/index.html":     "/about.html",
+	}
+	if len(build.Assets) != 3+len(expected) {
+		t.Fatalf("expected %d assets, got %v", 3+len(expected), assetPaths(build.Assets))
+	}
+
+	for stubPath, target := range expected {
+		stubs := build.Assets.Filter(WithPath(stubPath))
+		if len(stubs) != 1 {
+			t.Errorf("expected a stub page at %s", stubPath)
+			continue
+		}
+		stub := stubs[0]
+		data := string(stub.Data)
+		for _, want := range []string{
+			`<link rel="canonical" href="` + target + `">`,
+			`<meta http-equiv="refresh" content="0; url=` + target + `">`,
+		} {
+			if !strings.Contains(data, want) {
+				t.Errorf("stub %s does not contain %q:\n%s", stubPath, want, data)
+			}
+		}
+		if stub.Meta["SitemapExclude"] != true {
+			t.Errorf("stub %s is not excluded from the sitemap", stubPath)
+		}
+	}
+}
+
+func TestBuild_AddAliases_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets Assets
+	}{
+		{"replaces asset", Assets{
+			newTestAsset("/new.html", "", map[string]any{"Aliases": []string{"/index.html"}}),
+			newTestAsset("/index.html", "", nil),
+		}},
+		{"alias used twice", Assets{
+			newTestAsset("/a.html", "", map[string]any{"Aliases": []string{"/old"}}),
+			newTestAsset("/b.html", "", map[string]any{"Aliases": []string{"/old/"}}),
+		}},
+		{"aliases with the same stub", Assets{
+			newTestAsset("/a.html", "", map[string]any{"Aliases": []string{"/old"}}),
+			newTestAsset("/b.html", "", map[string]any{"Aliases": []string{"/old/index.html"}}),
+		}},
+		{"not a list", Assets{newTestAsset("/a.html", "", map[string]any{"Aliases": 1})}},
+		{"not a string", Assets{newTestAsset("/a.html", "", map[string]any{"Aliases": []any{1}})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&Build{Assets: tt.assets}).AddAliases(); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestBuild_AddRedirects(t *testing.T) {
+	tests := []struct {
+		format      RedirectFormat
+		contentType string
+		expected    string
+	}{
+		{
+			format:      RedirectsNetlify,
+			contentType: "text/plain",
+			expected: "/2019/post.html /blog/new-post/ 301\n" +
+				"/blog/old-post /blog/new-post/ 301\n" +
+				"/old-about /about.html 301\n",
+		},
+		{
+			format:      RedirectsJSON,
+			contentType: "application/json",
+			expected: `{
+  "redirects": [
+    {
+      "source": "/2019/post.html",
+      "destination": "/blog/new-post/",
+      "permanent": true
+    },
+    {
+      "source": "/blog/old-post",
+      "destination": "/blog/new-post/",
+      "permanent": true
+    },
+    {
+      "source": "/old-about",
+      "destination": "/about.html",
+      "permanent": true
+    }
+  ]
+}
+`,
+		},
+		{
+			format:      RedirectsNginx,
+			contentType: "text/plain",
+			expected: "rewrite ^/2019/post\\.html$ /blog/new-post/ permanent;\n" +
+				"rewrite ^/blog/old-post/?$ /blog/new-post/ permanent;\n" +
+				"rewrite ^/old-about/?$ /about.html permanent;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			build := newRedirectTestBuild()
+			if err := build.AddRedirects("redirects", tt.format); err != nil {
+				t.Fatalf("AddRedirects() returned error: %v", err)
+			}
+
+			redirects := build.Assets.Filter(WithPath("/redirects"))
+			if len(redirects) != 1 {
+				t.Fatalf("expected a /redirects asset, got %v", assetPaths(build.Assets))
+			}
+			if string(redirects[0].Data) != tt.expected {
+				t.Errorf("data = %q, want %q", redirects[0].Data, tt.expected)
+			}
+			if redirects[0].Meta["ContentType"] != tt.contentType {
+				t.Errorf("ContentType = %v, want %s", redirects[0].Meta["ContentType"], tt.contentType)
+			}
+			if redirects[0].Meta["SitemapExclude"] != true {
+				t.Error("redirects are not excluded from the sitemap")
+			}
+		})
+	}
+
+	if err := newRedirectTestBuild().AddRedirects("/redirects", "apache"); err == nil {
+		t.Error("expected error for unknown format, got nil")
+	}
+
+	// paths these formats can't write are rejected rather than written broken
+	unsafe := []struct {
+		format RedirectFormat
+		alias  string
+	}{
+		{RedirectsNetlify, "/old page"},
+		{RedirectsNginx, "/old page"},
+		{RedirectsNginx, "/old;page"},
+		{RedirectsNginx, "/old{page}"},
+	}
+	for _, tt := range unsafe {
+		build := &Build{Assets: Assets{newTestAsset("/new.html", "", map[string]any{"Aliases": []string{tt.alias}})}}
+		if err := build.AddRedirects("/redirects", tt.format); err == nil {
+			t.Errorf("expected error for alias %q in %s format, got nil", tt.alias, tt.format)
+		}
+	}
+	spaced := &Build{Assets: Assets{newTestAsset("/new page.html", "", map[string]any{"Aliases": []string{"/old"}})}}
+	if err := spaced.AddRedirects("/redirects", RedirectsNetlify); err == nil {
+		t.Error("expected error for a target with a space, got nil")
+	}
+	if err := spaced.AddRedirects("/vercel.json", RedirectsJSON); err != nil {
+		t.Errorf("AddRedirects() with JSON, which can hold any path, returned error: %v", err)
+	}
+
+	empty := &Build{Assets: Assets{newTestAsset("/index.html", "", nil)}}
+	if err := empty.AddRedirects("/vercel.json", RedirectsJSON); err != nil {
+		t.Fatalf("AddRedirects() returned error: %v", err)
+	}
+	if data := string(empty.Assets[1].Data); data != "{\n  \"redirects\": []\n}\n" {
+		t.Errorf("data without aliases = %q", data)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/xml"
 	"fmt"
+	"time"
 )
 
 func xmlEscape(value string) string {
@@ -12,6 +13,9 @@ func xmlEscape(value string) string {
 	return buf.String()
 }
 
+// AddSitemap adds a sitemap.xml listing the assets matching filters, except
+// those with "SitemapExclude" meta. Each asset is listed at url followed by its
+// "URL" meta, as set by PrettyURLTransformer, or else its Path.
 func (build *Build) AddSitemap(url string, filters ...Filter) error {
 	if len(build.Assets) == 0 {
 		return nil
@@ -25,15 +29,23 @@ func (build *Build) AddSitemap(url string, filters ...Filter) error {
 
 	for _, asset := range build.Assets.Filter(filters...) {
 		data = append(data, []byte("<url>")...)
-		data = append(data, []byte("<loc>"+xmlEscape(url+asset.Path)+"</loc>")...)
+		loc := asset.Path
+		if assetURL, ok := asset.Meta["URL"].(string); ok && assetURL != "" {
+			loc = assetURL
+		}
+		data = append(data, []byte("<loc>"+xmlEscape(url+loc)+"</loc>")...)
 
-		acceptableModifiedKeys := []string{"SitemapLastModified", "LastModified"}
+		acceptableModifiedKeys := []string{"SitemapLastModified", "LastModified", "ModTime"}
 		for _, key := range acceptableModifiedKeys {
 			if modified, ok := asset.Meta[key]; ok {
 				if modifiedStr, ok := modified.(string); ok {
 					data = append(data, []byte("<lastmod>"+xmlEscape(modifiedStr)+"</lastmod>")...)
 					break
 				}
+				if modifiedTime, ok := modified.(time.Time); ok && !modifiedTime.IsZero() {
+					data = append(data, []byte("<lastmod>"+xmlEscape(modifiedTime.Format(time.RFC3339))+"</lastmod>")...)
+					break
+				}
 			}
 		}
 
@@ -3,6 +3,7 @@ package sitetools
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestAddSitemap(t *testing.T) {
@@ -103,6 +104,28 @@ func TestAddSitemap_EscapingAndPriorityTypes(t *testing.T) {
 	}
 }
 
+func TestAddSitemap_ModTimeFallback(t *testing.T) {
+	modTime := time.Date(2025, 8, 2, 10, 30, 0, 0, time.UTC)
+	build := &Build{
+		Assets: Assets{
+			&Asset{Path: "/mtime.html", Meta: map[string]any{"ModTime": modTime}},
+			&Asset{Path: "/both.html", Meta: map[string]any{"ModTime": modTime, "LastModified": "2025-01-01"}},
+			&Asset{Path: "/zero.html", Meta: map[string]any{"ModTime": time.Time{}}},
+		},
+	}
+
+	err := build.AddSitemap("https://test.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sitemap := build.Assets.Filter(WithPath("/sitemap.xml"))[0]
+
+	expectedData := `<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://test.com/mtime.html</loc><lastmod>2025-08-02T10:30:00Z</lastmod></url><url><loc>https://test.com/both.html</loc><lastmod>2025-01-01</lastmod></url><url><loc>https://test.com/zero.html</loc></url></urlset>`
+	if string(sitemap.Data) != expectedData {
+		t.Errorf("Sitemap data does not match expected.\nGot:\n%s\nExpected:\n%s", string(sitemap.Data), expectedData)
+	}
+}
+
 func TestAddSitemap_EmptyBuild(t *testing.T) {
 	build := &Build{Assets: Assets{}}
 
@@ -0,0 +1,163 @@
+package sitetools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SyncResult describes the changes Sync made to an output directory. Paths are
+// in the form of an asset's Path, e.g. "/blog/index.html".
+type SyncResult struct {
+	// Added are the files that did not exist before.
+	Added []string
+	// Changed are the files whose contents were replaced.
+	Changed []string
+	// Removed are the files that no asset is written to anymore.
+	Removed []string
+	// Unchanged is the number of files that already had the right contents.
+	Unchanged int
+}
+
+// Sync is like Write, but makes outDir match the assets exactly: files no
+// asset is written to are removed, along with any directories left empty, and
+// files that already have the right contents are not written again. Each file
+// is written to a temporary file first and renamed into place, so nothing
+// reading outDir sees a partially written file.
+//
+// As a mistaken outDir would lose everything in it, Sync refuses to prune the
+// working directory or one containing it, or a directory holding a ".git" or
+// an IgnoreFile, which are more likely sources than output. Use SyncForce to
+// sync such a directory anyway.
+func (assets Assets) Sync(outDir string) (SyncResult, error) {
+	return assets.sync(outDir, false)
+}
+
+// SyncForce is like Sync, but doesn't check whether outDir looks like an output
+// directory before removing the files in it that no asset is written to.
+func (assets Assets) SyncForce(outDir string) (SyncResult, error) {
+	return assets.sync(outDir, true)
+}
+
+func (assets Assets) sync(outDir string, force bool) (SyncResult, error) {
+	var result SyncResult
+
+	baseDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return result, err
+	}
+	if !force {
+		if err := checkSyncDir(baseDir); err != nil {
+			return result, err
+		}
+	}
+
+	names, byName, err := assets.outputFiles()
+	if err != nil {
+		return result, err
+	}
+
+	// prune first, so a stale file doesn't stand where a directory is needed,
+	// or the other way around
+	var dirs []string
+	err = filepath.WalkDir(baseDir, func(target string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if target == baseDir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if target == baseDir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(baseDir, target)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			dirs = append(dirs, target)
+			return nil
+		}
+		if _, ok := byName[name]; ok {
+			return nil
+		}
+
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+		result.Removed = append(result.Removed, "/"+name)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// deepest first, so a directory holding only empty directories goes too
+	for _, dir := range slices.Backward(dirs) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return result, err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(dir); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	for _, name := range names {
+		data := byName[name].Data
+		target := filepath.Join(baseDir, filepath.FromSlash(name))
+
+		existing, err := os.ReadFile(target)
+		switch {
+		case err == nil && bytes.Equal(existing, data):
+			result.Unchanged++
+			continue
+		case err == nil:
+			result.Changed = append(result.Changed, "/"+name)
+		case errors.Is(err, fs.ErrNotExist):
+			result.Added = append(result.Added, "/"+name)
+		default:
+			return result, err
+		}
+
+		if err := writeFileAtomic(target, data); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// checkSyncDir returns an error if dir looks like it holds anything besides
+// output: it is or contains the working directory, or holds a ".git" or an
+// IgnoreFile.
+func checkSyncDir(dir string) error {
+	// compare real paths, as either may be reached through a symlink
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(wd); err == nil {
+			wd = resolved
+		}
+		if rel, err := filepath.Rel(dir, wd); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to sync %s: it contains the working directory", dir)
+		}
+	}
+	for _, name := range []string{".git", IgnoreFile} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return fmt.Errorf("refusing to sync %s: it holds %s", dir, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package sitetools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAssets_Sync(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "public")
+
+	first := Assets{
+		newTestAsset("/index.html", "home", nil),
+		newTestAsset("/about.html", "about", nil),
+		newTestAsset("/blog/old/post.html", "old post", nil),
+		newTestAsset("/feed", "feed file", nil),
+	}
+	result, err := first.Sync(outDir)
+	if err != nil {
+		t.Fatalf("Sync() returned error: %v", err)
+	}
+	expected := SyncResult{Added: []string{"/about.html", "/blog/old/post.html", "/feed", "/index.html"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("first Sync() = %+v, want %+v", result, expected)
+	}
+
+	// make unchanged files recognisable, to check they are not rewritten
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(outDir, "index.html"), past, past); err != nil {
+		t.Fatalf("failed to set file time: %v", err)
+	}
+	writeTestFile(t, outDir, "stray/file.txt", "not from an asset")
+
+	second := Assets{
+		newTestAsset("/index.html", "home", nil),
+		newTestAsset("/about.html", "about, updated", nil),
+		newTestAsset("/feed/index.xml", "feed dir", nil),
+		newTestAsset("/contact.html", "contact", nil),
+	}
+	result, err = second.Sync(outDir)
+	if err != nil {
+		t.Fatalf("Sync() returned error: %v", err)
+	}
+	expected = SyncResult{
+		Added:     []string{"/contact.html", "/feed/index.xml"},
+		Changed:   []string{"/about.html"},
+		Removed:   []string{"/blog/old/post.html", "/feed", "/stray/file.txt"},
+		Unchanged: 1,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("second Sync() = %+v, want %+v", result, expected)
+	}
+
+	info, err := os.Stat(filepath.Join(outDir, "index.html"))
+	if err != nil {
+		t.Fatalf("failed to stat index.html: %v", err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("unchanged file was rewritten")
+	}
+
+	var files []string
+	err = filepath.WalkDir(outDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(outDir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk output: %v", err)
+	}
+	expectedFiles := []string{".", "about.html", "contact.html", "feed", "feed/index.xml", "index.html"}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("output = %v, want %v", files, expectedFiles)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "about.html"))
+	if err != nil || string(data) != "about, updated" {
+		t.Errorf("about.html = %q, %v", data, err)
+	}
+}
+
+func TestAssets_Sync_Empty(t *testing.T) {
+	outDir := t.TempDir()
+	writeTestFile(t, outDir, "a/b/c.html", "stale")
+
+	result, err := Assets{}.Sync(outDir)
+	if err != nil {
+		t.Fatalf("Sync() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Removed, []string{"/a/b/c.html"}) {
+		t.Errorf("Removed = %v", result.Removed)
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatalf("output dir was removed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected empty output dir, got %d entries", len(entries))
+	}
+}
+
+func TestAssets_Sync_RefusesSourceDirs(t *testing.T) {
+	assets := Assets{newTestAsset("/index.html", "home", nil)}
+
+	for _, marker := range []string{".git/HEAD", IgnoreFile} {
+		t.Run(marker, func(t *testing.T) {
+			outDir := t.TempDir()
+			writeTestFile(t, outDir, marker, "keep")
+			writeTestFile(t, outDir, "main.go", "package main")
+
+			if _, err := assets.Sync(outDir); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if _, err := os.Stat(filepath.Join(outDir, "main.go")); err != nil {
+				t.Errorf("Sync() removed a file despite refusing: %v", err)
+			}
+
+			result, err := assets.SyncForce(outDir)
+			if err != nil {
+				t.Fatalf("SyncForce() returned error: %v", err)
+			}
+			if !slices.Contains(result.Removed, "/main.go") {
+				t.Errorf("SyncForce() Removed = %v, want /main.go among them", result.Removed)
+			}
+		})
+	}
+
+	t.Run("working directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "site/main.go", "package main")
+		t.Chdir(filepath.Join(dir, "site"))
+
+		for _, outDir := range []string{".", "..", filepath.Join(dir, "site")} {
+			if _, err := assets.Sync(outDir); err == nil {
+				t.Errorf("Sync(%q) from the working directory returned nil error", outDir)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "site", "main.go")); err != nil {
+			t.Errorf("Sync() removed a file despite refusing: %v", err)
+		}
+
+		if _, err := assets.Sync("public"); err != nil {
+			t.Errorf("Sync() into a subdirectory returned error: %v", err)
+		}
+	})
+}
@@ -5,12 +5,22 @@ import (
 	"fmt"
 	"maps"
 	"path"
+	"slices"
 	"text/template"
+	"text/template/parse"
 )
 
+// TemplateTransformer executes each asset as a text/template, with the
+// Components of the same file type available to it as named templates. The
+// components the asset calls, directly or through other components, are
+// recorded in its "Dependencies" meta along with Data, see Build.Affected.
 type TemplateTransformer struct {
 	Components map[string]*Asset
 	Global     map[string]any
+	// Data are the assets Global was built from, such as the data files
+	// returned by Build.PopData. As any asset may use them, they are recorded
+	// as dependencies of every asset.
+	Data Assets
 }
 
 func (t TemplateTransformer) Transform(asset *Asset) error {
@@ -29,6 +39,9 @@ func (t TemplateTransformer) Transform(asset *Asset) error {
 		return err
 	}
 
+	// the component each template was defined in, so the ones reached when
+	// executing can be recorded
+	owners := map[string]*Asset{}
 	for name, component := range t.Components {
 		if path.Ext(component.Path) != path.Ext(asset.Path) {
 			continue
@@ -37,6 +50,14 @@ func (t TemplateTransformer) Transform(asset *Asset) error {
 		if err != nil {
 			return err
 		}
+		// parsed on its own too, to tell which templates it defines
+		own, err := template.New(name).Parse(string(component.Data))
+		if err != nil {
+			return err
+		}
+		for _, t := range own.Templates() {
+			owners[t.Name()] = component
+		}
 	}
 
 	buf := &bytes.Buffer{}
@@ -45,6 +66,58 @@ func (t TemplateTransformer) Transform(asset *Asset) error {
 	}
 
 	asset.Data = buf.Bytes()
+	addDependencies(asset, usedComponents(tmpl, "", owners)...)
+	addDependencies(asset, t.Data...)
 
 	return nil
 }
+
+// usedComponents returns the components that executing the template called
+// name can reach through {{template}} and {{block}} actions, going by the
+// owners of the templates.
+func usedComponents(tmpl *template.Template, name string, owners map[string]*Asset) []*Asset {
+	seen := map[string]bool{}
+	var used []*Asset
+
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		if owner, ok := owners[name]; ok && !slices.Contains(used, owner) {
+			used = append(used, owner)
+		}
+		if t := tmpl.Lookup(name); t != nil && t.Tree != nil {
+			walkTemplateCalls(t.Tree.Root, visit)
+		}
+	}
+	visit(name)
+
+	return used
+}
+
+// walkTemplateCalls calls fn with the name of each template that node calls.
+func walkTemplateCalls(node parse.Node, fn func(name string)) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			walkTemplateCalls(child, fn)
+		}
+	case *parse.TemplateNode:
+		fn(node.Name)
+	case *parse.IfNode:
+		walkTemplateCalls(node.List, fn)
+		walkTemplateCalls(node.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateCalls(node.List, fn)
+		walkTemplateCalls(node.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateCalls(node.List, fn)
+		walkTemplateCalls(node.ElseList, fn)
+	}
+}
@@ -1,5 +1,36 @@
 package sitetools
 
+import "context"
+
 type Transformer interface {
 	Transform(*Asset) error
 }
+
+// ContextTransformer is an optional interface for transformers that can stop
+// early when a context is cancelled. Assets.TransformContext uses
+// TransformContext in place of Transform for transformers that implement it.
+type ContextTransformer interface {
+	Transformer
+	TransformContext(ctx context.Context, asset *Asset) error
+}
+
+// AdaptContext returns t as a ContextTransformer. Transformers that do not
+// implement ContextTransformer themselves are wrapped so that they check the
+// context before starting, but cannot be interrupted once running.
+func AdaptContext(t Transformer) ContextTransformer {
+	if ct, ok := t.(ContextTransformer); ok {
+		return ct
+	}
+	return contextAdapter{t}
+}
+
+type contextAdapter struct {
+	Transformer
+}
+
+func (a contextAdapter) TransformContext(ctx context.Context, asset *Asset) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Transform(asset)
+}
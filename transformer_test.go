@@ -0,0 +1,43 @@
+package sitetools
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockContextTransformer struct {
+	MockTransformer
+	ContextCalledCount int
+}
+
+func (m *mockContextTransformer) TransformContext(ctx context.Context, asset *Asset) error {
+	m.ContextCalledCount++
+	return m.Transform(asset)
+}
+
+func TestAdaptContext(t *testing.T) {
+	plain := &MockTransformer{}
+	adapted := AdaptContext(plain)
+
+	if err := adapted.TransformContext(context.Background(), &Asset{}); err != nil {
+		t.Fatalf("TransformContext() returned error: %v", err)
+	}
+	if plain.CalledCount != 1 {
+		t.Errorf("wrapped transformer was called %d times, want 1", plain.CalledCount)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := adapted.TransformContext(ctx, &Asset{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("TransformContext() with cancelled context = %v, want %v", err, context.Canceled)
+	}
+	if plain.CalledCount != 1 {
+		t.Errorf("wrapped transformer was called after cancellation")
+	}
+
+	native := &mockContextTransformer{}
+	if AdaptContext(native) != ContextTransformer(native) {
+		t.Error("AdaptContext() wrapped a transformer that already implements ContextTransformer")
+	}
+}
@@ -32,16 +32,30 @@ func (t WrapperTemplateTransformer) Transform(asset *Asset) error {
 		Meta: map[string]any{},
 	}
 	maps.Copy(wrappedAsset.Meta, t.Template.Meta)
+	// the page is still the child, not the template's source file, even when
+	// the child has no source of its own, e.g. from GeneratePages
+	for _, key := range sourceMetaKeys {
+		delete(wrappedAsset.Meta, key)
+	}
 	maps.Copy(wrappedAsset.Meta, asset.Meta)
+	// the wrapped asset is the child, so starts from the child's dependencies;
+	// the components reached from the template, the child's own calls among
+	// them, are added when it is executed
+	delete(wrappedAsset.Meta, "Dependencies")
+	if deps := dependencies(asset); deps != nil {
+		wrappedAsset.Meta["Dependencies"] = deps
+	}
 
 	transformer := TemplateTransformer{
 		Global:     t.Global,
 		Components: wrapperComponents,
+		Data:       t.Data,
 	}
 
 	if err := transformer.Transform(&wrappedAsset); err != nil {
 		return err
 	}
+	addDependencies(&wrappedAsset, t.Template)
 
 	*asset = wrappedAsset
 